@@ -0,0 +1,733 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"examtopics-downloader/internal/config"
+	"examtopics-downloader/internal/fetch"
+	"examtopics-downloader/internal/utils"
+)
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+func availableCommands() []command {
+	return []command{
+		{
+			name:    "list-providers",
+			usage:   "list-providers [--json]",
+			summary: "List every provider found on ExamTopics",
+			run:     runListProviders,
+		},
+		{
+			name:    "list-exams",
+			usage:   "list-exams <provider> [--json]",
+			summary: "List exam slugs for a provider and where each one was found",
+			run:     runListExams,
+		},
+		{
+			name:    "download",
+			usage:   "download --provider <name> (--exam <slug> | --all-exams) [--out <dir>] [--resume] [--incremental [--refresh-changed]] [--json]",
+			summary: "Download an exam without interactive menus",
+			run:     runDownload,
+		},
+		{
+			name:    "batch",
+			usage:   "batch <manifest> [--out <dir>] [--parallel <n>] [--resume] [--json]",
+			summary: "Download every exam listed in a manifest file",
+			run:     runBatch,
+		},
+		{
+			name:    "render",
+			usage:   "render <dataset.json> [--out <dir>] [--provider <name>] [--exam <slug>] [--no-comments] [--json]",
+			summary: "Re-render a saved dataset to HTML without network access",
+			run:     runRender,
+		},
+	}
+}
+
+func runCommand(ctx context.Context, name string, args []string) error {
+	for _, cmd := range availableCommands() {
+		if cmd.name == name {
+			return cmd.run(ctx, args)
+		}
+	}
+	return withExitCode(exitUsage, fmt.Errorf("unknown command %q (run with -h for usage)", name))
+}
+
+func printUsage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "Usage:")
+	fmt.Fprintln(out, "  examtopics-downloader [flags]              interactive menus")
+	fmt.Fprintln(out, "  examtopics-downloader <command> [flags]")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Commands:")
+	for _, cmd := range availableCommands() {
+		fmt.Fprintf(out, "  %-16s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Flags:")
+	flag.PrintDefaults()
+}
+
+// commandOptions holds the flags shared by every subcommand.
+type commandOptions struct {
+	debug     bool
+	json      bool
+	noCache   bool
+	cacheOnly bool
+	record    string
+	replay    string
+	baseURL   string
+	proxy     string
+	proxyList string
+	cookies   string
+	headers   string
+}
+
+func newCommandFlagSet(cmdName string) (*flag.FlagSet, *commandOptions) {
+	opts := &commandOptions{}
+	fs := flag.NewFlagSet(cmdName, flag.ExitOnError)
+	fs.BoolVar(&opts.debug, "debug", false, "Enable debug logs")
+	fs.BoolVar(&opts.json, "json", false, "Print machine-readable JSON to stdout")
+	fs.BoolVar(&opts.noCache, "no-cache", false, "Bypass the on-disk HTTP response cache")
+	fs.BoolVar(&opts.cacheOnly, "cache-only", false, "Serve every page from the HTTP cache and never touch the network")
+	fs.StringVar(&opts.record, "record", "", "Save every fetched page to this fixture archive")
+	fs.StringVar(&opts.replay, "replay", "", "Serve pages from this fixture archive instead of the network")
+	fs.StringVar(&opts.baseURL, "base-url", "", "Scrape this origin instead of "+utils.DefaultBaseURL+" (env "+baseURLEnv+")")
+	fs.StringVar(&opts.proxy, "proxy", "", "Send requests through these comma-separated http(s):// or socks5:// proxies (env "+proxiesEnv+")")
+	fs.StringVar(&opts.proxyList, "proxy-list", "", "Rotate requests over the proxies listed in this file, one per line")
+	fs.StringVar(&opts.headers, "header-profile", "", headerProfileUsage)
+	fs.StringVar(&opts.cookies, "cookies", "", "Import browser cookies (Netscape cookies.txt or JSON export) into the saved session")
+
+	for _, cmd := range availableCommands() {
+		if cmd.name != cmdName {
+			continue
+		}
+		fs.Usage = func() {
+			fmt.Fprintf(fs.Output(), "Usage: examtopics-downloader %s\n\n", cmd.usage)
+			fs.PrintDefaults()
+		}
+	}
+
+	return fs, opts
+}
+
+// parseCommandArgs parses flags that may appear before or after positional
+// arguments (e.g. "list-exams aws --json") and returns the positionals.
+func parseCommandArgs(fs *flag.FlagSet, opts *commandOptions, args []string) ([]string, error) {
+	var positional []string
+	for {
+		_ = fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	if opts.debug {
+		fetch.SetDebug(true)
+	}
+	if opts.json {
+		statusOut = os.Stderr
+		fetch.SetOutput(os.Stderr)
+	}
+	if err := applyCacheFlags(opts.noCache, opts.cacheOnly); err != nil {
+		return nil, err
+	}
+	if err := applyProxyFlags(opts.proxy, opts.proxyList); err != nil {
+		return nil, err
+	}
+	// Cookies are imported for the scraped origin, so resolve it first.
+	if opts.baseURL != "" {
+		if err := applyBaseURL(opts.baseURL); err != nil {
+			return nil, err
+		}
+	}
+	// A new origin also needs the configured cookie export imported for it.
+	if opts.cookies != "" || (opts.baseURL != "" && settings.CookiesFile != "") {
+		if err := applySession(opts.cookies); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyHeaderProfile(opts.headers); err != nil {
+		return nil, err
+	}
+	if err := applyFixtureFlags(opts.record, opts.replay); err != nil {
+		return nil, err
+	}
+
+	return positional, nil
+}
+
+func applyCacheFlags(noCache, cacheOnly bool) error {
+	switch {
+	case noCache && cacheOnly:
+		return withExitCode(exitUsage, fmt.Errorf("--no-cache and --cache-only cannot be combined"))
+	case noCache:
+		fetch.SetCacheMode(fetch.CacheDisabled)
+	case cacheOnly:
+		fetch.SetCacheMode(fetch.CacheOnly)
+	}
+	return nil
+}
+
+// baseURLEnv overrides the scraped origin when --base-url is not given.
+const baseURLEnv = config.EnvPrefix + "BASE_URL"
+
+// applyBaseURL sets the scraped origin from the flag value, falling back to
+// base_url from the config file or environment.
+func applyBaseURL(flagValue string) error {
+	value := strings.TrimSpace(flagValue)
+	if value == "" {
+		value = settings.BaseURL
+	}
+	if value == "" {
+		return nil
+	}
+	if err := utils.SetBaseURL(value); err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	return nil
+}
+
+// proxiesEnv replaces the proxies from the config file when --proxy and
+// --proxy-list are not given.
+const proxiesEnv = config.EnvPrefix + "PROXIES"
+
+// applyProxyFlags replaces the configured proxies with those from --proxy
+// and --proxy-list. Without either flag the config and environment apply.
+func applyProxyFlags(proxy, proxyList string) error {
+	var proxies []string
+	for _, entry := range strings.Split(proxy, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			proxies = append(proxies, entry)
+		}
+	}
+	if path := strings.TrimSpace(proxyList); path != "" {
+		listed, err := utils.ReadProxyList(path)
+		if err != nil {
+			return withExitCode(exitUsage, err)
+		}
+		proxies = append(proxies, listed...)
+	}
+	if len(proxies) == 0 {
+		return nil
+	}
+
+	if err := utils.SetProxies(proxies); err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	installDefaultFetcher()
+	return nil
+}
+
+var headerProfileUsage = "Browser header profiles to send, comma-separated and rotated per request: " +
+	strings.Join(fetch.HeaderProfileNames(), ", ") + ", a custom profile from the config, or " + fetch.AllHeaderProfiles
+
+// applyHeaderProfile selects the header profiles named by the flag,
+// overriding header_profile from the config.
+func applyHeaderProfile(flagValue string) error {
+	if strings.TrimSpace(flagValue) == "" {
+		return nil
+	}
+	if err := fetch.SetHeaderProfiles(settings.HeaderProfiles, strings.Split(flagValue, ",")); err != nil {
+		return withExitCode(exitUsage, err)
+	}
+	return nil
+}
+
+// sessionJar holds the persisted session cookies once applySession has run.
+var sessionJar *fetch.CookieJar
+
+// applySession opens the saved session, imports the cookie export from the
+// flag (or cookies_file from the config), and sends the cookies with every
+// request. An empty jar browses as a guest.
+func applySession(cookiesFlag string) error {
+	if sessionJar == nil {
+		jar, err := fetch.OpenCookieJar(fetch.DefaultCookieJarPath())
+		if err != nil {
+			return withExitCode(exitBadConfig, err)
+		}
+		sessionJar = jar
+	}
+
+	exportPath := strings.TrimSpace(cookiesFlag)
+	if exportPath == "" {
+		exportPath = settings.CookiesFile
+	}
+	if exportPath != "" {
+		imported, err := sessionJar.Import(exportPath)
+		if err != nil {
+			return withExitCode(exitUsage, err)
+		}
+		if cookiesFlag != "" {
+			printInfof("Imported %d cookie(s) from %s\n", imported, exportPath)
+		}
+	}
+
+	if sessionJar.Len() == 0 {
+		return nil
+	}
+	fetch.SetCookieJar(sessionJar)
+	installDefaultFetcher()
+	return nil
+}
+
+func warnProxyRemoved(proxy string, err error) {
+	printWarnf("Proxy %s taken out of rotation: %v\n", proxy, err)
+}
+
+// installDefaultFetcher rebuilds the HTTP fetcher so it picks up new
+// timeouts or proxies. An active recording is rebuilt around it; a replay
+// never touches the network and is left alone.
+func installDefaultFetcher() {
+	switch {
+	case fixtureReplayServer != nil:
+	case fixtureRecorder != nil:
+		fixtureRecorder = fetch.NewRecordingFetcher(fetch.NewHTTPFetcher(nil))
+		fetch.SetFetcher(fixtureRecorder)
+	default:
+		fetch.SetFetcher(nil)
+	}
+}
+
+// Fixture state set up by --record/--replay and torn down by finishFixtures.
+var (
+	fixtureRecorder     *fetch.RecordingFetcher
+	fixtureRecordPath   string
+	fixtureReplayServer *fetch.ReplayServer
+)
+
+func applyFixtureFlags(record, replay string) error {
+	record, replay = strings.TrimSpace(record), strings.TrimSpace(replay)
+	switch {
+	case record != "" && replay != "":
+		return withExitCode(exitUsage, fmt.Errorf("--record and --replay cannot be combined"))
+	case replay != "" && fixtureReplayServer == nil:
+		archive, err := fetch.LoadFixtureArchive(replay)
+		if err != nil {
+			return withExitCode(exitUsage, err)
+		}
+		fixtureReplayServer = fetch.NewReplayServer(archive)
+		fetch.SetFetcher(fixtureReplayServer.Fetcher())
+		// Keep replays deterministic: no cached pages in, none out.
+		fetch.SetCacheMode(fetch.CacheDisabled)
+	case record != "" && fixtureRecorder == nil:
+		fixtureRecorder = fetch.NewRecordingFetcher(fetch.NewHTTPFetcher(nil))
+		fixtureRecordPath = record
+		fetch.SetFetcher(fixtureRecorder)
+	}
+	return nil
+}
+
+// finishFixtures saves a recording, even for a failed or interrupted run, and
+// stops the replay server.
+func finishFixtures() error {
+	if fixtureReplayServer != nil {
+		if misses := fixtureReplayServer.Misses(); len(misses) > 0 {
+			printWarnf("%d page(s) were not in the fixture archive, e.g. %s\n", len(misses), misses[0])
+		}
+		fixtureReplayServer.Close()
+		fixtureReplayServer = nil
+	}
+	if fixtureRecorder != nil {
+		if err := fixtureRecorder.Save(fixtureRecordPath); err != nil {
+			return err
+		}
+		printSuccessf("Recorded %d page(s) to %s\n", fixtureRecorder.Len(), fixtureRecordPath)
+		fixtureRecorder = nil
+	}
+	return nil
+}
+
+func printJSON(v any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func runListProviders(ctx context.Context, args []string) error {
+	fs, opts := newCommandFlagSet("list-providers")
+	positional, err := parseCommandArgs(fs, opts, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return withExitCode(exitUsage, fmt.Errorf("list-providers takes no arguments"))
+	}
+
+	providers := getProvidersWithStatus(ctx)
+	if len(providers) == 0 {
+		return fmt.Errorf("could not load the provider list from ExamTopics")
+	}
+
+	type providerEntry struct {
+		Provider string `json:"provider"`
+		Name     string `json:"name"`
+	}
+	entries := make([]providerEntry, 0, len(providers))
+	for _, provider := range providers {
+		entries = append(entries, providerEntry{Provider: provider, Name: formatProviderName(provider)})
+	}
+
+	if opts.json {
+		return printJSON(entries)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tNAME")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\n", entry.Provider, entry.Name)
+	}
+	return w.Flush()
+}
+
+func runListExams(ctx context.Context, args []string) error {
+	fs, opts := newCommandFlagSet("list-exams")
+	positional, err := parseCommandArgs(fs, opts, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return withExitCode(exitUsage, fmt.Errorf("list-exams requires exactly one provider"))
+	}
+	provider := strings.TrimSpace(strings.ToLower(positional[0]))
+
+	if err := validateProvider(ctx, provider); err != nil {
+		return err
+	}
+
+	options := fetch.GetProviderExamOptions(ctx, provider)
+	if err := ctx.Err(); err != nil {
+		return withExitCode(exitInterrupted, fmt.Errorf("exam discovery aborted: %w", err))
+	}
+	if opts.json {
+		return printJSON(struct {
+			Provider string             `json:"provider"`
+			Exams    []fetch.ExamOption `json:"exams"`
+		}{Provider: provider, Exams: options})
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "EXAM\tSOURCE")
+	for _, option := range options {
+		fmt.Fprintf(w, "%s\t%s\n", option.Slug, option.Source)
+	}
+	return w.Flush()
+}
+
+func runDownload(ctx context.Context, args []string) error {
+	fs, opts := newCommandFlagSet("download")
+	provider := fs.String("provider", "", "Provider to download (e.g. aws)")
+	exam := fs.String("exam", "", "Exam slug to download (e.g. saa-c03)")
+	outDir := fs.String("out", "", "Directory for generated files (default: current directory)")
+	resume := fs.Bool("resume", false, "Continue an interrupted download from its checkpoint")
+	incremental := fs.Bool("incremental", false, "Only fetch questions missing from the previously saved dataset")
+	refreshChanged := fs.Bool("refresh-changed", false, "With --incremental, also refetch questions whose comment count changed")
+	allExams := fs.Bool("all-exams", false, "Download every exam of the provider into one directory with an index.html")
+	positional, err := parseCommandArgs(fs, opts, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		return withExitCode(exitUsage, fmt.Errorf("unexpected arguments: %s", strings.Join(positional, " ")))
+	}
+
+	dlOpts := downloadOptions{
+		OutDir:         *outDir,
+		Resume:         *resume,
+		Incremental:    *incremental,
+		RefreshChanged: *refreshChanged,
+	}
+	if *allExams {
+		result, err := runAllExams(ctx, *provider, *exam, dlOpts)
+		if err != nil {
+			return err
+		}
+		if opts.json {
+			return printJSON(result)
+		}
+		return nil
+	}
+
+	result, err := runNonInteractive(ctx, *provider, *exam, dlOpts)
+	if err != nil {
+		return err
+	}
+
+	if opts.json {
+		return printJSON(result)
+	}
+	return nil
+}
+
+// defaultBatchParallel is how many batch jobs run at once. They share one
+// limiter, so more jobs do not mean more requests per second.
+const defaultBatchParallel = 3
+
+type batchJobResult struct {
+	downloadResult
+	Line     int    `json:"line"`
+	Error    string `json:"error,omitempty"`
+	ExitCode int    `json:"exit_code,omitempty"`
+}
+
+type batchSummary struct {
+	Jobs      []batchJobResult     `json:"jobs"`
+	Succeeded int                  `json:"succeeded"`
+	Failed    int                  `json:"failed"`
+	Throttled *fetch.ThrottleStats `json:"throttled,omitempty"`
+}
+
+func runBatch(ctx context.Context, args []string) error {
+	fs, opts := newCommandFlagSet("batch")
+	outDir := fs.String("out", "", "Directory for jobs without their own out= (default: current directory)")
+	parallel := fs.Int("parallel", defaultBatchParallel, "How many exams to download at the same time")
+	resume := fs.Bool("resume", false, "Continue interrupted jobs from their checkpoints")
+	positional, err := parseCommandArgs(fs, opts, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return withExitCode(exitUsage, fmt.Errorf("batch requires exactly one manifest file"))
+	}
+	if *parallel < 1 {
+		return withExitCode(exitUsage, fmt.Errorf("--parallel must be at least 1"))
+	}
+
+	jobs, err := config.LoadManifest(positional[0])
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	ctx, stop := withInterrupt(ctx)
+	defer stop()
+
+	fetch.ResetStats()
+	summary := batchSummary{Jobs: make([]batchJobResult, len(jobs))}
+	runnable := validateBatchJobs(ctx, jobs, *outDir, summary.Jobs)
+
+	printInfof("Downloading %d exam(s), %d at a time...\n", len(runnable), *parallel)
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, *parallel)
+	for _, i := range runnable {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			job := jobs[i]
+			result, err := downloadExam(ctx, job.Provider, job.Exam, downloadOptions{
+				OutDir:         batchJobOutDir(job, *outDir),
+				Resume:         *resume,
+				Incremental:    job.Incremental,
+				RefreshChanged: job.RefreshChanged,
+				NoComments:     job.NoComments,
+				KeepStats:      true,
+				Quiet:          *parallel > 1,
+			})
+			summary.Jobs[i].downloadResult = result
+			if err != nil {
+				summary.Jobs[i].Error = err.Error()
+				summary.Jobs[i].ExitCode = exitCodeFor(err)
+				printErrorf("%s / %s failed: %v\n", job.Provider, job.Exam, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, job := range summary.Jobs {
+		if job.Error == "" {
+			summary.Succeeded++
+		} else {
+			summary.Failed++
+		}
+	}
+	if throttle := fetch.Throttle(); throttle.Events > 0 {
+		summary.Throttled = &throttle
+	}
+
+	if opts.json {
+		if err := printJSON(summary); err != nil {
+			return err
+		}
+	} else {
+		printBatchSummary(summary)
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		return withExitCode(exitInterrupted, fmt.Errorf("batch interrupted; finished jobs were saved"))
+	case summary.Failed > 0:
+		return fmt.Errorf("%d of %d batch job(s) failed", summary.Failed, len(jobs))
+	}
+	return nil
+}
+
+// batchJobOutDir is the directory a job writes into: its own out=, else the
+// batch's --out.
+func batchJobOutDir(job config.ManifestJob, outDir string) string {
+	if job.OutDir != "" {
+		return job.OutDir
+	}
+	return outDir
+}
+
+// validateBatchJobs checks every provider once and every exam against its
+// provider's exam list, recording failures in results. Jobs that would write
+// the same files as an earlier job, once their output directories are
+// resolved, fail too. It returns the indexes of the jobs that can run.
+func validateBatchJobs(ctx context.Context, jobs []config.ManifestJob, outDir string, results []batchJobResult) []int {
+	providerErrs := map[string]error{}
+	claimed := map[string]int{}
+	var runnable []int
+	for i, job := range jobs {
+		results[i] = batchJobResult{
+			downloadResult: downloadResult{Provider: job.Provider, Exam: job.Exam},
+			Line:           job.Line,
+		}
+
+		providerErr, checked := providerErrs[job.Provider]
+		if !checked {
+			providerErr = validateProvider(ctx, job.Provider)
+			providerErrs[job.Provider] = providerErr
+		}
+		err := providerErr
+		if err == nil {
+			err = validateExam(ctx, job.Provider, job.Exam)
+		}
+		var statePath string
+		if err == nil {
+			statePath = batchJobStatePath(job, outDir)
+			if first, dup := claimed[statePath]; dup {
+				err = withExitCode(exitUsage, fmt.Errorf("writes the same files as line %d", first))
+			}
+		}
+		if err != nil {
+			results[i].Error = err.Error()
+			results[i].ExitCode = exitCodeFor(err)
+			printErrorf("Skipping %s / %s (line %d): %v\n", job.Provider, job.Exam, job.Line, err)
+			continue
+		}
+		claimed[statePath] = job.Line
+		runnable = append(runnable, i)
+	}
+	return runnable
+}
+
+// batchJobStatePath resolves where a job keeps its dataset, so jobs are
+// compared by the files they write rather than by how out= was spelled.
+func batchJobStatePath(job config.ManifestJob, outDir string) string {
+	dir := batchJobOutDir(job, outDir)
+	if strings.TrimSpace(dir) == "" {
+		dir = settings.OutputDir
+	}
+	path := filepath.Join(dir, defaultStatePath(job.Provider, job.Exam))
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+func printBatchSummary(summary batchSummary) {
+	fmt.Fprintln(statusOut)
+	w := tabwriter.NewWriter(statusOut, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PROVIDER\tEXAM\tQUESTIONS\tFAILED PAGES\tRESULT")
+	for _, job := range summary.Jobs {
+		status := "ok"
+		switch {
+		case job.Error != "":
+			status = job.Error
+		case job.Partial:
+			status = "partial"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%s\n", job.Provider, job.Exam, job.Questions, job.Failures, status)
+	}
+	w.Flush()
+
+	if summary.Throttled != nil {
+		printWarnf("Throttled %d time(s) during the batch.\n", summary.Throttled.Events)
+	}
+	if summary.Failed == 0 {
+		printSuccessf("All %d batch job(s) finished.\n", summary.Succeeded)
+		return
+	}
+	printWarnf("%d job(s) finished, %d failed.\n", summary.Succeeded, summary.Failed)
+}
+
+func runRender(_ context.Context, args []string) error {
+	fs, opts := newCommandFlagSet("render")
+	outDir := fs.String("out", "", "Directory for the HTML file (default: next to the dataset)")
+	provider := fs.String("provider", "", "Provider shown in the page header (default: from the dataset)")
+	exam := fs.String("exam", "", "Exam shown in the page header (default: from the dataset)")
+	noComments := fs.Bool("no-comments", !settings.IncludeComments, "Leave community comments out of the HTML")
+	positional, err := parseCommandArgs(fs, opts, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return withExitCode(exitUsage, fmt.Errorf("render requires exactly one dataset file"))
+	}
+	datasetPath := positional[0]
+
+	dataset, err := utils.LoadDataset(datasetPath)
+	if err != nil {
+		return err
+	}
+	if len(dataset.Questions) == 0 {
+		return withExitCode(exitNoQuestions, fmt.Errorf("dataset %q contains no questions", datasetPath))
+	}
+	if strings.TrimSpace(*provider) == "" {
+		*provider = dataset.Provider
+	}
+	if strings.TrimSpace(*exam) == "" {
+		*exam = dataset.ExamSlug
+	}
+
+	outputPath := strings.TrimSuffix(datasetPath, filepath.Ext(datasetPath)) + ".html"
+	if strings.TrimSpace(*outDir) != "" {
+		outputPath, err = resolveOutputPath(*outDir, filepath.Base(outputPath))
+		if err != nil {
+			return err
+		}
+	}
+
+	savedFiles, err := utils.WriteDataWithOptions(dataset.Questions, outputPath, utils.RenderOptions{
+		IncludeComments: !*noComments,
+		Provider:        *provider,
+		Exam:            *exam,
+		Partial:         dataset.Partial,
+	})
+	if err != nil {
+		return withExitCode(exitWriteFailed, fmt.Errorf("failed writing output: %w", err))
+	}
+	printSuccessf("Rendered %d question(s) to %s\n", len(dataset.Questions), strings.Join(savedFiles, ", "))
+
+	if opts.json {
+		return printJSON(struct {
+			Dataset   string   `json:"dataset"`
+			Questions int      `json:"questions"`
+			Files     []string `json:"files"`
+		}{Dataset: datasetPath, Questions: len(dataset.Questions), Files: savedFiles})
+	}
+	return nil
+}
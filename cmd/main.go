@@ -2,16 +2,23 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"math"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"examtopics-downloader/internal/config"
 	"examtopics-downloader/internal/fetch"
+	"examtopics-downloader/internal/models"
 	"examtopics-downloader/internal/utils"
 )
 
@@ -25,29 +32,170 @@ const (
 	ansiGray   = "\x1b[90m"
 )
 
+// Exit codes returned by the CLI so scripted runs can tell failures apart.
+// Usage errors share code 2 with the flag package.
+const (
+	exitGenericFailure  = 1
+	exitUsage           = 2
+	exitUnknownProvider = 3
+	exitUnknownExam     = 4
+	exitNoQuestions     = 5
+	exitWriteFailed     = 6
+	exitBadConfig       = 7
+	// exitInterrupted follows the shell convention for SIGINT (128 + 2).
+	exitInterrupted = 130
+)
+
 var useANSI = detectANSI()
 
+// settings is the effective configuration: defaults, config file and
+// EXAMTOPICS_* environment overrides. Command-line flags win over it.
+var settings = config.Default()
+
+// statusOut receives human-readable progress output. Commands running with
+// --json point it at stderr so stdout only carries the JSON document.
+var statusOut io.Writer = os.Stdout
+
+// interactive is false when the run was fully described by flags; it
+// suppresses the banner and the "Press Enter" pause on errors.
+var interactive = true
+
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &exitError{code: code, err: err}
+}
+
+func exitCodeFor(err error) int {
+	var coded *exitError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	return exitGenericFailure
+}
+
 func main() {
 	defer func() {
 		if r := recover(); r != nil {
 			printErrorf("Unexpected error: %v\n", r)
 			pauseBeforeExitOnError()
-			os.Exit(1)
+			os.Exit(exitGenericFailure)
 		}
 	}()
 
 	if err := run(); err != nil {
 		printErrorf("Error: %v\n", err)
 		pauseBeforeExitOnError()
-		os.Exit(1)
+		os.Exit(exitCodeFor(err))
 	}
 }
 
-func run() error {
+func run() (err error) {
+	flag.Usage = printUsage
 	debug := flag.Bool("debug", false, "Enable debug logs")
+	provider := flag.String("provider", "", "Provider to download without interactive menus (e.g. aws)")
+	exam := flag.String("exam", "", "Exam slug to download without interactive menus (e.g. saa-c03)")
+	outDir := flag.String("out", "", "Directory for generated files (default: current directory)")
+	resume := flag.Bool("resume", false, "Continue an interrupted download from its checkpoint")
+	incremental := flag.Bool("incremental", false, "Only fetch questions missing from the previously saved dataset")
+	refreshChanged := flag.Bool("refresh-changed", false, "With --incremental, also refetch questions whose comment count changed")
+	noCache := flag.Bool("no-cache", false, "Bypass the on-disk HTTP response cache")
+	cacheOnly := flag.Bool("cache-only", false, "Serve every page from the HTTP cache and never touch the network")
+	timeout := flag.Duration("timeout", 0, "Abort the whole run after this long, keeping partial output (e.g. 2h; 0 disables)")
+	record := flag.String("record", "", "Save every fetched page to this fixture archive")
+	replay := flag.String("replay", "", "Serve pages from this fixture archive instead of the network")
+	baseURL := flag.String("base-url", "", "Scrape this origin instead of "+utils.DefaultBaseURL+" (env "+baseURLEnv+")")
+	configPath := flag.String("config", "", "Read settings from this JSON file instead of searching for "+config.FileName)
+	proxy := flag.String("proxy", "", "Send requests through these comma-separated http(s):// or socks5:// proxies (env "+proxiesEnv+")")
+	proxyList := flag.String("proxy-list", "", "Rotate requests over the proxies listed in this file, one per line")
+	headerProfile := flag.String("header-profile", "", headerProfileUsage)
+	cookies := flag.String("cookies", "", "Import browser cookies (Netscape cookies.txt or JSON export) into the saved session")
+	allExams := flag.Bool("all-exams", false, "With --provider, download every exam of the provider into one directory with an index.html")
 	flag.Parse()
 	fetch.SetDebug(*debug)
+	utils.SetProxyRemovedHook(warnProxyRemoved)
+	if err := loadSettings(*configPath); err != nil {
+		return err
+	}
+	if err := applyProxyFlags(*proxy, *proxyList); err != nil {
+		return err
+	}
+	// Cookies are imported for the scraped origin, so resolve it first.
+	if err := applyBaseURL(*baseURL); err != nil {
+		return err
+	}
+	if err := applySession(*cookies); err != nil {
+		return err
+	}
+	if err := applyHeaderProfile(*headerProfile); err != nil {
+		return err
+	}
+	if err := applyCacheFlags(*noCache, *cacheOnly); err != nil {
+		return err
+	}
+	defer func() {
+		if finishErr := finishFixtures(); finishErr != nil && err == nil {
+			err = finishErr
+		}
+	}()
+	if err := applyFixtureFlags(*record, *replay); err != nil {
+		return err
+	}
 
+	dlOpts := downloadOptions{
+		OutDir:         *outDir,
+		Resume:         *resume,
+		Incremental:    *incremental,
+		RefreshChanged: *refreshChanged,
+	}
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	if flag.NArg() > 0 {
+		interactive = false
+		ctx, stop := withInterrupt(ctx)
+		defer stop()
+		return runCommand(ctx, flag.Arg(0), flag.Args()[1:])
+	}
+
+	if *allExams {
+		interactive = false
+		ctx, stop := withInterrupt(ctx)
+		defer stop()
+		_, err := runAllExams(ctx, *provider, *exam, dlOpts)
+		return err
+	}
+
+	if strings.TrimSpace(*provider) != "" || strings.TrimSpace(*exam) != "" {
+		interactive = false
+		ctx, stop := withInterrupt(ctx)
+		defer stop()
+		_, err := runNonInteractive(ctx, *provider, *exam, dlOpts)
+		return err
+	}
+
+	if err := validateRefreshChanged(dlOpts); err != nil {
+		return err
+	}
 	printBanner()
 
 	reader := bufio.NewReader(os.Stdin)
@@ -55,7 +203,7 @@ func run() error {
 	selectedProvider, err := promptSelectionWithRefresh(
 		reader,
 		"Available Providers",
-		getProvidersWithStatus,
+		func() []string { return getProvidersWithStatus(ctx) },
 		formatProviderName,
 	)
 	if err != nil {
@@ -65,7 +213,7 @@ func run() error {
 	selectedExam, err := promptSelectionWithRefresh(
 		reader,
 		fmt.Sprintf("Available Exams for %s", formatProviderName(selectedProvider)),
-		func() []string { return getProviderExamSlugsWithStatus(selectedProvider) },
+		func() []string { return getProviderExamSlugsWithStatus(ctx, selectedProvider) },
 		func(s string) string {
 			if s == "all-discussions" {
 				return "all-discussions (fallback)"
@@ -76,32 +224,562 @@ func run() error {
 	if err != nil {
 		return fmt.Errorf("failed reading exam selection: %w", err)
 	}
+
+	_, err = downloadExam(ctx, selectedProvider, selectedExam, dlOpts)
+	return err
+}
+
+// withInterrupt cancels ctx on the first Ctrl+C so work can wind down and
+// partial output can be saved. The handler is removed once that happens, so
+// a second Ctrl+C terminates the process as usual.
+func withInterrupt(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(parent, os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+func runNonInteractive(ctx context.Context, provider, exam string, opts downloadOptions) (downloadResult, error) {
+	provider = strings.TrimSpace(strings.ToLower(provider))
+	exam = strings.TrimSpace(strings.ToLower(exam))
+	if provider == "" || exam == "" {
+		return downloadResult{}, withExitCode(exitUsage, fmt.Errorf("--provider and --exam must be used together"))
+	}
+	if err := validateRefreshChanged(opts); err != nil {
+		return downloadResult{}, err
+	}
+
+	if err := validateProvider(ctx, provider); err != nil {
+		return downloadResult{}, err
+	}
+	if err := validateExam(ctx, provider, exam); err != nil {
+		return downloadResult{}, err
+	}
+
+	return downloadExam(ctx, provider, exam, opts)
+}
+
+func validateProvider(ctx context.Context, provider string) error {
+	printInfof("Validating provider %q...\n", provider)
+	providers := fetch.GetAllProviders(ctx)
+	if err := ctx.Err(); err != nil {
+		return withExitCode(exitInterrupted, fmt.Errorf("provider validation aborted: %w", err))
+	}
+	if len(providers) == 0 {
+		return fmt.Errorf("could not load the provider list from ExamTopics")
+	}
+	for _, p := range providers {
+		if p == provider {
+			return nil
+		}
+	}
+
+	message := fmt.Sprintf("unknown provider %q", provider)
+	if suggestions := suggestOptions(providers, provider); len(suggestions) > 0 {
+		message += fmt.Sprintf(" (did you mean: %s?)", strings.Join(suggestions, ", "))
+	}
+	return withExitCode(exitUnknownProvider, errors.New(message))
+}
+
+func validateExam(ctx context.Context, provider, exam string) error {
+	printInfof("Validating exam %q for %s...\n", exam, formatProviderName(provider))
+	examSlugs := fetch.GetProviderExamSlugs(ctx, provider)
+	if err := ctx.Err(); err != nil {
+		return withExitCode(exitInterrupted, fmt.Errorf("exam validation aborted: %w", err))
+	}
+	if len(examSlugs) == 0 {
+		return fmt.Errorf("could not load the exam list for %s from ExamTopics", formatProviderName(provider))
+	}
+	for _, slug := range examSlugs {
+		if slug == exam {
+			return nil
+		}
+	}
+
+	message := fmt.Sprintf("unknown exam %q for provider %q", exam, provider)
+	if suggestions := suggestOptions(examSlugs, exam); len(suggestions) > 0 {
+		message += fmt.Sprintf(" (did you mean: %s?)", strings.Join(suggestions, ", "))
+	}
+	return withExitCode(exitUnknownExam, errors.New(message))
+}
+
+// suggestOptions returns up to five options containing the input, used to
+// make "unknown provider/exam" errors actionable.
+func suggestOptions(options []string, input string) []string {
+	const maxSuggestions = 5
+
+	input = strings.TrimSpace(strings.ToLower(input))
+	if input == "" {
+		return nil
+	}
+
+	out := make([]string, 0, maxSuggestions)
+	for _, opt := range options {
+		if strings.Contains(opt, input) || strings.Contains(input, opt) {
+			out = append(out, opt)
+			if len(out) == maxSuggestions {
+				break
+			}
+		}
+	}
+	return out
+}
+
+type downloadOptions struct {
+	OutDir string
+	// Resume reuses questions journaled by an interrupted earlier run.
+	Resume bool
+	// Incremental loads the dataset saved by an earlier download and only
+	// fetches questions that are new since then.
+	Incremental    bool
+	RefreshChanged bool
+	// NoComments leaves comments out of the HTML even if include_comments
+	// is on.
+	NoComments bool
+	// KeepStats keeps the throttling counters of the batch download the job
+	// belongs to.
+	KeepStats bool
+	// Quiet hides the extraction progress bar, for jobs run side by side.
+	Quiet bool
+}
+
+// validateRefreshChanged rejects --refresh-changed without --incremental,
+// where it would have nothing to refresh.
+func validateRefreshChanged(opts downloadOptions) error {
+	if opts.RefreshChanged && !opts.Incremental {
+		return withExitCode(exitUsage, fmt.Errorf("--refresh-changed needs --incremental"))
+	}
+	return nil
+}
+
+type downloadResult struct {
+	Provider  string               `json:"provider"`
+	Exam      string               `json:"exam"`
+	Questions int                  `json:"questions"`
+	Resumed   int                  `json:"resumed"`
+	Added     int                  `json:"added,omitempty"`
+	Removed   int                  `json:"removed,omitempty"`
+	Updated   int                  `json:"updated,omitempty"`
+	Partial   bool                 `json:"partial,omitempty"`
+	Throttled *fetch.ThrottleStats `json:"throttled,omitempty"`
+	Failures  int                  `json:"failures,omitempty"`
+	// SessionExpired is set when the imported session stopped working.
+	SessionExpired bool `json:"session_expired,omitempty"`
+	// Types counts the questions of each type, e.g. multiple_choice.
+	Types []utils.QuestionTypeCount `json:"types,omitempty"`
+	Files []string                  `json:"files"`
+}
+
+func downloadExam(ctx context.Context, selectedProvider, selectedExam string, opts downloadOptions) (downloadResult, error) {
+	result := downloadResult{Provider: selectedProvider, Exam: selectedExam}
+
 	extractionFilter := selectedExam
 	if selectedExam == "all-discussions" {
 		extractionFilter = ""
 	}
 
-	printInfof("Starting extraction for %s / %s...\n", formatProviderName(selectedProvider), selectedExam)
-	links := fetch.GetAllPages(selectedProvider, extractionFilter)
-	if len(links) == 0 {
-		return fmt.Errorf("no matching questions were extracted")
+	outDir := opts.OutDir
+	if strings.TrimSpace(outDir) == "" {
+		outDir = settings.OutputDir
+	}
+	outputPath, err := resolveOutputPath(outDir, defaultOutputPath(selectedProvider, selectedExam))
+	if err != nil {
+		return result, err
 	}
+	statePath := filepath.Join(filepath.Dir(outputPath), defaultStatePath(selectedProvider, selectedExam))
+
+	checkpoint, err := openCheckpoint(statePath, opts.Resume)
+	if err != nil {
+		return result, err
+	}
+	defer checkpoint.Close()
+
+	extractOpts := fetch.ExtractOptions{
+		Checkpoint:     checkpoint,
+		RefreshChanged: opts.RefreshChanged,
+		KeepStats:      opts.KeepStats,
+		Quiet:          opts.Quiet,
+	}
+	if opts.Incremental {
+		previous, err := loadPreviousQuestions(statePath)
+		if err != nil {
+			return result, err
+		}
+		extractOpts.Previous = previous
+	}
+
+	ctx, stop := withInterrupt(ctx)
+	defer stop()
 
-	outputPath := defaultOutputPath(selectedProvider, selectedExam)
 	headerExam := selectedExam
 	if selectedExam == "all-discussions" {
 		headerExam = ""
 	}
-	savedFiles, err := utils.WriteDataWithSelection(links, outputPath, true, selectedProvider, headerExam)
+
+	printInfof("Starting extraction for %s / %s...\n", formatProviderName(selectedProvider), selectedExam)
+	extracted := fetch.GetAllPagesWithOptions(ctx, selectedProvider, extractionFilter, extractOpts)
+	links := extracted.Questions
+
+	// Report failures first: they explain an empty result as well.
+	result.Failures = len(extracted.Failures)
+	failureFile, err := utils.WriteFailureReport(models.NewFailureReport(selectedProvider, headerExam, extracted.Failures), statePath)
 	if err != nil {
-		return fmt.Errorf("failed writing output: %w", err)
+		printWarnf("Could not save the failure report: %v\n", err)
+	}
+	printFailureSummary(extracted.Failures, failureFile)
+
+	if len(links) == 0 {
+		if extracted.Partial {
+			return result, withExitCode(exitInterrupted, fmt.Errorf("extraction aborted before any question finished: %w", ctx.Err()))
+		}
+		return result, withExitCode(exitNoQuestions, fmt.Errorf("no matching questions were extracted"))
+	}
+	result.Questions = len(links)
+	result.Resumed = extracted.Resumed
+	result.Added = extracted.Added
+	result.Removed = extracted.Removed
+	result.Updated = extracted.Updated
+	result.Partial = extracted.Partial
+	if extracted.Throttle.Events > 0 {
+		result.Throttled = &extracted.Throttle
+	}
+	result.SessionExpired = extracted.SessionExpired
+	result.Types = utils.CountQuestionTypes(links)
+	printQuestionTypes(result.Types)
+	if extracted.Partial {
+		printWarnf("Extraction aborted (%v); saving the %d question(s) finished so far as partial output.\n", ctx.Err(), len(links))
+	}
+
+	savedFiles, err := writeExamFiles(outputPath, statePath, selectedProvider, headerExam, links, extracted.Partial, opts)
+	if err != nil {
+		return result, err
+	}
+	if failureFile != "" {
+		savedFiles = append(savedFiles, failureFile)
+	}
+	result.Files = savedFiles
+
+	if extracted.Partial {
+		printWarnf("Saved partial output: %s\n", strings.Join(savedFiles, ", "))
+		printInfof("Run the same command with --resume to finish it (checkpoint: %s).\n", checkpoint.Path())
+		return result, withExitCode(exitInterrupted, fmt.Errorf("extraction interrupted; partial output saved"))
+	}
+
+	// The output is complete, so the journal is no longer needed.
+	if err := checkpoint.Remove(); err != nil {
+		printWarnf("Could not remove checkpoint %s: %v\n", checkpoint.Path(), err)
 	}
 
 	printSuccessf("Successfully saved output: %s\n", strings.Join(savedFiles, ", "))
+	return result, nil
+}
+
+// writeExamFiles saves the HTML page of one exam at outputPath and its
+// dataset next to statePath, and returns their paths.
+func writeExamFiles(outputPath, statePath, provider, exam string, questions []models.QuestionData, partial bool, opts downloadOptions) ([]string, error) {
+	savedFiles, err := utils.WriteDataWithOptions(questions, outputPath, utils.RenderOptions{
+		IncludeComments: settings.IncludeComments && !opts.NoComments,
+		Provider:        provider,
+		Exam:            exam,
+		Partial:         partial,
+	})
+	if err != nil {
+		return nil, withExitCode(exitWriteFailed, fmt.Errorf("failed writing output: %w", err))
+	}
+
+	dataset := models.NewDataset(provider, exam, questions)
+	dataset.Partial = partial
+	datasetFile, err := utils.WriteDataset(dataset, statePath)
+	if err != nil {
+		return nil, withExitCode(exitWriteFailed, fmt.Errorf("failed writing dataset: %w", err))
+	}
+	return append(savedFiles, datasetFile), nil
+}
+
+// allExamsResult reports a --all-exams download: one entry per exam page
+// plus the provider-wide index and failure report in Files.
+type allExamsResult struct {
+	Provider  string               `json:"provider"`
+	Exams     []examFilesResult    `json:"exams"`
+	Questions int                  `json:"questions"`
+	Resumed   int                  `json:"resumed"`
+	Partial   bool                 `json:"partial,omitempty"`
+	Throttled *fetch.ThrottleStats `json:"throttled,omitempty"`
+	Failures  int                  `json:"failures,omitempty"`
+	// SessionExpired is set when the imported session stopped working.
+	SessionExpired bool                      `json:"session_expired,omitempty"`
+	Types          []utils.QuestionTypeCount `json:"types,omitempty"`
+	Files          []string                  `json:"files"`
+}
+
+type examFilesResult struct {
+	Exam      string                    `json:"exam"`
+	Questions int                       `json:"questions"`
+	Types     []utils.QuestionTypeCount `json:"types,omitempty"`
+	Files     []string                  `json:"files"`
+}
+
+func runAllExams(ctx context.Context, provider, exam string, opts downloadOptions) (allExamsResult, error) {
+	provider = strings.TrimSpace(strings.ToLower(provider))
+	switch {
+	case provider == "":
+		return allExamsResult{}, withExitCode(exitUsage, fmt.Errorf("--all-exams needs --provider"))
+	case strings.TrimSpace(exam) != "":
+		return allExamsResult{}, withExitCode(exitUsage, fmt.Errorf("--all-exams and --exam cannot be combined"))
+	case opts.Incremental:
+		return allExamsResult{}, withExitCode(exitUsage, fmt.Errorf("--all-exams does not support --incremental"))
+	case opts.RefreshChanged:
+		return allExamsResult{}, withExitCode(exitUsage, fmt.Errorf("--all-exams does not support --refresh-changed"))
+	}
+
+	if err := validateProvider(ctx, provider); err != nil {
+		return allExamsResult{}, err
+	}
+	return downloadAllExams(ctx, provider, opts)
+}
+
+// downloadAllExams extracts every discussion of the provider in one pass
+// and writes one page per exam into a provider directory, together with an
+// index.html linking them. Failures and the checkpoint cover the whole run
+// and live next to the index.
+func downloadAllExams(ctx context.Context, provider string, opts downloadOptions) (allExamsResult, error) {
+	result := allExamsResult{Provider: provider}
+
+	outDir := opts.OutDir
+	if strings.TrimSpace(outDir) == "" {
+		outDir = settings.OutputDir
+	}
+	providerDir := sanitizeFilenameSegment(provider)
+	if strings.TrimSpace(outDir) != "" {
+		providerDir = filepath.Join(outDir, providerDir)
+	}
+	indexPath, err := resolveOutputPath(providerDir, "index.html")
+	if err != nil {
+		return result, err
+	}
+
+	checkpoint, err := openCheckpoint(indexPath, opts.Resume)
+	if err != nil {
+		return result, err
+	}
+	defer checkpoint.Close()
+
+	ctx, stop := withInterrupt(ctx)
+	defer stop()
+
+	printInfof("Starting extraction of every exam for %s...\n", formatProviderName(provider))
+	extracted := fetch.GetAllPagesWithOptions(ctx, provider, "", fetch.ExtractOptions{
+		Checkpoint: checkpoint,
+		KeepStats:  opts.KeepStats,
+		Quiet:      opts.Quiet,
+	})
+
+	result.Failures = len(extracted.Failures)
+	failureFile, err := utils.WriteFailureReport(models.NewFailureReport(provider, "", extracted.Failures), indexPath)
+	if err != nil {
+		printWarnf("Could not save the failure report: %v\n", err)
+	}
+	printFailureSummary(extracted.Failures, failureFile)
+
+	if len(extracted.Questions) == 0 {
+		if extracted.Partial {
+			return result, withExitCode(exitInterrupted, fmt.Errorf("extraction aborted before any question finished: %w", ctx.Err()))
+		}
+		return result, withExitCode(exitNoQuestions, fmt.Errorf("no questions were extracted"))
+	}
+	result.Questions = len(extracted.Questions)
+	result.Resumed = extracted.Resumed
+	result.Partial = extracted.Partial
+	if extracted.Throttle.Events > 0 {
+		result.Throttled = &extracted.Throttle
+	}
+	result.SessionExpired = extracted.SessionExpired
+	result.Types = utils.CountQuestionTypes(extracted.Questions)
+	printQuestionTypes(result.Types)
+	if extracted.Partial {
+		printWarnf("Extraction aborted (%v); saving the %d question(s) finished so far as partial output.\n", ctx.Err(), result.Questions)
+	}
+
+	groups := fetch.GroupQuestionsByExam(provider, extracted.Questions)
+	entries := make([]utils.ExamIndexEntry, 0, len(groups))
+	for _, group := range groups {
+		examPath := filepath.Join(providerDir, defaultOutputPath(provider, group.Slug))
+		statePath := filepath.Join(providerDir, defaultStatePath(provider, group.Slug))
+		files, err := writeExamFiles(examPath, statePath, provider, group.Slug, group.Questions, extracted.Partial, opts)
+		if err != nil {
+			return result, err
+		}
+		result.Exams = append(result.Exams, examFilesResult{
+			Exam:      group.Slug,
+			Questions: len(group.Questions),
+			Types:     utils.CountQuestionTypes(group.Questions),
+			Files:     files,
+		})
+		entries = append(entries, utils.ExamIndexEntry{
+			Exam:      group.Slug,
+			File:      filepath.Base(files[0]),
+			Questions: len(group.Questions),
+		})
+	}
+
+	indexFile, err := utils.WriteExamIndex(providerDir, provider, entries, extracted.Partial)
+	if err != nil {
+		return result, withExitCode(exitWriteFailed, err)
+	}
+	result.Files = []string{indexFile}
+	if failureFile != "" {
+		result.Files = append(result.Files, failureFile)
+	}
+
+	if extracted.Partial {
+		printWarnf("Saved partial output for %d exam(s): %s\n", len(groups), indexFile)
+		printInfof("Run the same command with --resume to finish it (checkpoint: %s).\n", checkpoint.Path())
+		return result, withExitCode(exitInterrupted, fmt.Errorf("extraction interrupted; partial output saved"))
+	}
+
+	if err := checkpoint.Remove(); err != nil {
+		printWarnf("Could not remove checkpoint %s: %v\n", checkpoint.Path(), err)
+	}
+
+	printSuccessf("Saved %d exam(s) with %d question(s); index: %s\n", len(groups), result.Questions, indexFile)
+	return result, nil
+}
+
+// printQuestionTypes prints how many questions of each type were found, so
+// exams with many hotspot or drag-and-drop questions stand out.
+func printQuestionTypes(counts []utils.QuestionTypeCount) {
+	if len(counts) == 0 {
+		return
+	}
+	parts := make([]string, 0, len(counts))
+	for _, count := range counts {
+		parts = append(parts, fmt.Sprintf("%d %s", count.Count, strings.ToLower(utils.QuestionTypeLabel(count.Type))))
+	}
+	printInfof("Question types: %s\n", strings.Join(parts, ", "))
+}
+
+// maxListedFailures caps how many failed pages are printed; the JSON report
+// always has all of them.
+const maxListedFailures = 10
+
+func printFailureSummary(failures []models.FetchFailure, reportPath string) {
+	if len(failures) == 0 {
+		return
+	}
+
+	lost := 0
+	for _, failure := range failures {
+		if failure.Page == "question" && !failure.KeptPrevious {
+			lost++
+		}
+	}
+	printWarnf("%d page(s) could not be fetched (%d question(s) missing from the output):\n", len(failures), lost)
+	for i, failure := range failures {
+		if i == maxListedFailures {
+			fmt.Fprintf(statusOut, "  ... and %d more\n", len(failures)-maxListedFailures)
+			break
+		}
+		kind := failure.Kind
+		if failure.Status != 0 {
+			kind = fmt.Sprintf("%s %d", kind, failure.Status)
+		}
+		fmt.Fprintf(statusOut, "  - %s [%s] %s\n", failure.URL, kind, failure.Reason)
+	}
+	if reportPath != "" {
+		printInfof("Failure report saved to %s\n", reportPath)
+	}
+}
+
+// loadPreviousQuestions reads the dataset an earlier download saved next to
+// outputPath. A missing dataset falls back to a full download.
+func loadPreviousQuestions(outputPath string) ([]models.QuestionData, error) {
+	datasetPath := utils.GetDatasetOutputPath(outputPath)
+	if _, err := os.Stat(datasetPath); errors.Is(err, os.ErrNotExist) {
+		printWarnf("No previous dataset at %s; downloading everything.\n", datasetPath)
+		return nil, nil
+	}
+
+	dataset, err := utils.LoadDataset(datasetPath)
+	if err != nil {
+		return nil, err
+	}
+	printInfof("Loaded %d question(s) from %s for incremental refresh.\n", len(dataset.Questions), datasetPath)
+	return append([]models.QuestionData{}, dataset.Questions...), nil
+}
+
+// openCheckpoint opens the journal kept next to outputPath. Without resume an
+// existing journal is discarded, with a hint on how to keep it next time.
+func openCheckpoint(outputPath string, resume bool) (*fetch.Checkpoint, error) {
+	checkpointPath := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".checkpoint.jsonl"
+
+	if !resume {
+		if stat, err := os.Stat(checkpointPath); err == nil && stat.Size() > 0 {
+			printWarnf("Discarding checkpoint from an earlier run: %s (use --resume to continue it)\n", checkpointPath)
+		}
+	}
+
+	checkpoint, err := fetch.OpenCheckpoint(checkpointPath, resume)
+	if err != nil {
+		return nil, withExitCode(exitWriteFailed, err)
+	}
+	if resume && checkpoint.Len() > 0 {
+		printInfof("Resuming with %d question(s) from %s\n", checkpoint.Len(), checkpointPath)
+	}
+	return checkpoint, nil
+}
+
+// resolveOutputPath places fileName inside outDir, creating the directory
+// when needed. An empty outDir keeps the file in the working directory.
+func resolveOutputPath(outDir, fileName string) (string, error) {
+	if strings.TrimSpace(outDir) == "" {
+		return fileName, nil
+	}
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return "", withExitCode(exitWriteFailed, fmt.Errorf("failed creating output directory: %w", err))
+	}
+	return filepath.Join(outDir, fileName), nil
+}
+
+// loadSettings reads the config file and environment and applies the
+// network tunables; output defaults are read from settings where used.
+func loadSettings(path string) error {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return withExitCode(exitBadConfig, err)
+	}
+	settings = cfg
+
+	fetch.SetTuning(fetch.Tuning{
+		RequestsPerSecond:     cfg.RequestsPerSecond,
+		MaxConcurrentRequests: cfg.MaxConcurrentRequests,
+		MaxRetries:            cfg.MaxRetries,
+		InitialBackoff:        time.Duration(cfg.InitialBackoff),
+		BackoffFactor:         cfg.BackoffFactor,
+	})
+	utils.SetHTTPTimeouts(utils.HTTPTimeouts{
+		Request:        time.Duration(cfg.HTTPTimeout),
+		TLSHandshake:   time.Duration(cfg.TLSHandshakeTimeout),
+		ResponseHeader: time.Duration(cfg.ResponseHeaderTimeout),
+		IdleConn:       time.Duration(cfg.IdleConnTimeout),
+	})
+	if err := utils.SetProxies(cfg.Proxies); err != nil {
+		return withExitCode(exitBadConfig, err)
+	}
+	if err := fetch.SetHeaderProfiles(cfg.HeaderProfiles, strings.Split(cfg.HeaderProfile, ",")); err != nil {
+		return withExitCode(exitBadConfig, fmt.Errorf("header_profile: %w", err))
+	}
+	// Rebuild the default fetcher so its client picks up the new timeouts.
+	installDefaultFetcher()
 	return nil
 }
 
 func pauseBeforeExitOnError() {
+	if !interactive {
+		return
+	}
+
 	stat, err := os.Stdin.Stat()
 	if err != nil {
 		return
@@ -110,13 +788,13 @@ func pauseBeforeExitOnError() {
 		return
 	}
 
-	fmt.Print(style("Press Enter to close...", ansiGray))
+	fmt.Fprint(statusOut, style("Press Enter to close...", ansiGray))
 	_, _ = bufio.NewReader(os.Stdin).ReadString('\n')
 }
 
-func getProvidersWithStatus() []string {
+func getProvidersWithStatus(ctx context.Context) []string {
 	printInfof("Loading providers from ExamTopics...\n")
-	fmt.Println(style("This may take a moment while data is fetched from exams and discussions.", ansiGray))
+	fmt.Fprintln(statusOut, style("This may take a moment while data is fetched from exams and discussions.", ansiGray))
 
 	done := make(chan struct{})
 	start := time.Now()
@@ -146,7 +824,7 @@ func getProvidersWithStatus() []string {
 		}
 	}()
 
-	providers := fetch.GetAllProviders()
+	providers := fetch.GetAllProviders(ctx)
 	close(done)
 
 	elapsed := time.Since(start).Round(time.Second)
@@ -154,10 +832,10 @@ func getProvidersWithStatus() []string {
 	return providers
 }
 
-func getProviderExamSlugsWithStatus(provider string) []string {
+func getProviderExamSlugsWithStatus(ctx context.Context, provider string) []string {
 	providerLabel := formatProviderName(provider)
 	printSection(fmt.Sprintf("Exam Discovery: %s", providerLabel))
-	fmt.Println(style("Scanning available exams (including discussion-derived variants).", ansiGray))
+	fmt.Fprintln(statusOut, style("Scanning available exams (including discussion-derived variants).", ansiGray))
 
 	done := make(chan struct{})
 	start := time.Now()
@@ -187,7 +865,7 @@ func getProviderExamSlugsWithStatus(provider string) []string {
 		}
 	}()
 
-	examSlugs := fetch.GetProviderExamSlugs(provider)
+	examSlugs := fetch.GetProviderExamSlugs(ctx, provider)
 	close(done)
 
 	elapsed := time.Since(start).Round(time.Second)
@@ -226,7 +904,7 @@ func promptSelectionWithRefresh(
 		}
 		printMenuHelp()
 
-		fmt.Print(style("Select> ", ansiBold+ansiCyan))
+		fmt.Fprint(statusOut, style("Select> ", ansiBold+ansiCyan))
 		raw, err := reader.ReadString('\n')
 		if err != nil {
 			return "", err
@@ -329,51 +1007,51 @@ func printOptionsInColumns(options []selectionOption) {
 			}
 			row.WriteString(style(fmt.Sprintf("%-*s", colWidth, lines[idx]), ansiCyan))
 		}
-		fmt.Println(strings.TrimRight(row.String(), " "))
+		fmt.Fprintln(statusOut, strings.TrimRight(row.String(), " "))
 	}
 }
 
 func printBanner() {
-	fmt.Println(style(strings.Repeat("=", 64), ansiGray))
-	fmt.Println(style(" ExamTopics Downloader - Interactive Exam Extractor", ansiBold+ansiCyan))
-	fmt.Println(style(strings.Repeat("=", 64), ansiGray))
-	fmt.Println()
+	fmt.Fprintln(statusOut, style(strings.Repeat("=", 64), ansiGray))
+	fmt.Fprintln(statusOut, style(" ExamTopics Downloader - Interactive Exam Extractor", ansiBold+ansiCyan))
+	fmt.Fprintln(statusOut, style(strings.Repeat("=", 64), ansiGray))
+	fmt.Fprintln(statusOut)
 }
 
 func printSection(title string) {
-	fmt.Println()
-	fmt.Println(style(strings.Repeat("-", 64), ansiGray))
-	fmt.Println(style(" "+title, ansiBold+ansiCyan))
-	fmt.Println(style(strings.Repeat("-", 64), ansiGray))
+	fmt.Fprintln(statusOut)
+	fmt.Fprintln(statusOut, style(strings.Repeat("-", 64), ansiGray))
+	fmt.Fprintln(statusOut, style(" "+title, ansiBold+ansiCyan))
+	fmt.Fprintln(statusOut, style(strings.Repeat("-", 64), ansiGray))
 }
 
 func printMenuHeader(title string, shown int, total int, filter string) {
 	printSection(title)
-	fmt.Println(style(fmt.Sprintf(" Showing %d of %d", shown, total), ansiGray))
+	fmt.Fprintln(statusOut, style(fmt.Sprintf(" Showing %d of %d", shown, total), ansiGray))
 	if strings.TrimSpace(filter) != "" {
-		fmt.Println(style(fmt.Sprintf(" Filter: %q", filter), ansiYellow))
+		fmt.Fprintln(statusOut, style(fmt.Sprintf(" Filter: %q", filter), ansiYellow))
 	}
-	fmt.Println()
+	fmt.Fprintln(statusOut)
 }
 
 func printMenuHelp() {
-	fmt.Println(style(" Commands: [number] select | /text filter | / clear | /refresh refetch", ansiGray))
+	fmt.Fprintln(statusOut, style(" Commands: [number] select | /text filter | / clear | /refresh refetch", ansiGray))
 }
 
 func printInfof(format string, args ...any) {
-	fmt.Printf(style("[INFO] ", ansiCyan)+format, args...)
+	fmt.Fprintf(statusOut, style("[INFO] ", ansiCyan)+format, args...)
 }
 
 func printSuccessf(format string, args ...any) {
-	fmt.Printf(style("[OK] ", ansiGreen)+format, args...)
+	fmt.Fprintf(statusOut, style("[OK] ", ansiGreen)+format, args...)
 }
 
 func printWarnf(format string, args ...any) {
-	fmt.Printf(style("[WARN] ", ansiYellow)+format, args...)
+	fmt.Fprintf(statusOut, style("[WARN] ", ansiYellow)+format, args...)
 }
 
 func printErrorf(format string, args ...any) {
-	fmt.Printf(style("[ERROR] ", ansiRed)+format, args...)
+	fmt.Fprintf(statusOut, style("[ERROR] ", ansiRed)+format, args...)
 }
 
 func style(text string, code string) string {
@@ -402,6 +1080,19 @@ func detectANSI() bool {
 }
 
 func defaultOutputPath(provider, examSlug string) string {
+	baseProvider, baseExamCode := outputNameSegments(provider, examSlug)
+	return settings.OutputBaseName(baseProvider, baseExamCode, time.Now()) + ".html"
+}
+
+// defaultStatePath names the dataset, checkpoint and failure report of an
+// exam. Unlike defaultOutputPath it leaves out {date}, so --incremental and
+// --resume find the files of an earlier day.
+func defaultStatePath(provider, examSlug string) string {
+	baseProvider, baseExamCode := outputNameSegments(provider, examSlug)
+	return settings.StateBaseName(baseProvider, baseExamCode) + ".html"
+}
+
+func outputNameSegments(provider, examSlug string) (string, string) {
 	baseProvider := sanitizeFilenameSegment(provider)
 	baseExamCode := sanitizeFilenameSegment(examSlug)
 	if baseProvider == "" {
@@ -410,8 +1101,7 @@ func defaultOutputPath(provider, examSlug string) string {
 	if baseExamCode == "" {
 		baseExamCode = "output"
 	}
-
-	return fmt.Sprintf("%s_%s.html", baseProvider, baseExamCode)
+	return baseProvider, baseExamCode
 }
 
 func sanitizeFilenameSegment(input string) string {
@@ -0,0 +1,329 @@
+// Package config loads the optional JSON configuration file and the
+// EXAMTOPICS_* environment overrides that tune request pacing, HTTP
+// timeouts and output defaults.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"examtopics-downloader/internal/constants"
+	"examtopics-downloader/internal/utils"
+)
+
+// FileName is the config file looked up in the working directory and in
+// <user config dir>/examtopics-downloader/.
+const FileName = "examtopics-downloader.json"
+
+// EnvPrefix prefixes every environment override, e.g.
+// EXAMTOPICS_REQUESTS_PER_SECOND.
+const EnvPrefix = "EXAMTOPICS_"
+
+// Filename pattern placeholders.
+const (
+	PatternProvider = "{provider}"
+	PatternExam     = "{exam}"
+	PatternDate     = "{date}"
+)
+
+// Config holds every tunable. Fields missing from the file keep their
+// defaults.
+type Config struct {
+	BaseURL string `json:"base_url"`
+
+	RequestsPerSecond     float64  `json:"requests_per_second"`
+	MaxConcurrentRequests int      `json:"max_concurrent_requests"`
+	MaxRetries            int      `json:"max_retries"`
+	InitialBackoff        Duration `json:"initial_backoff"`
+	BackoffFactor         float64  `json:"backoff_factor"`
+
+	HTTPTimeout           Duration `json:"http_timeout"`
+	TLSHandshakeTimeout   Duration `json:"tls_handshake_timeout"`
+	ResponseHeaderTimeout Duration `json:"response_header_timeout"`
+	IdleConnTimeout       Duration `json:"idle_conn_timeout"`
+
+	// Proxies are http://, https://, socks5:// or socks5h:// URLs. Several
+	// proxies are rotated per request.
+	Proxies []string `json:"proxies"`
+	// CookiesFile is a Netscape cookies.txt or JSON cookie export imported
+	// into the persisted session on every run.
+	CookiesFile string `json:"cookies_file"`
+
+	// HeaderProfile names the browser header profiles to send, comma
+	// separated, or "all"; several are rotated per request. Empty means
+	// "chrome".
+	HeaderProfile string `json:"header_profile"`
+	// HeaderProfiles defines custom profiles: name -> header -> value.
+	HeaderProfiles map[string]map[string]string `json:"header_profiles"`
+
+	OutputDir       string `json:"output_dir"`
+	IncludeComments bool   `json:"include_comments"`
+	// FilenamePattern names output files without extension, e.g.
+	// "{provider}_{exam}".
+	FilenamePattern string `json:"filename_pattern"`
+
+	// Source is the file the config was read from, empty for defaults.
+	Source string `json:"-"`
+}
+
+// Duration is a time.Duration written as a Go duration string ("1.5s").
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("durations must be strings such as \"1s\" or \"500ms\"")
+	}
+	parsed, err := time.ParseDuration(raw)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// Default returns the built-in settings.
+func Default() Config {
+	return Config{
+		RequestsPerSecond:     constants.RequestsPerSecond,
+		MaxConcurrentRequests: constants.MaxConcurrentRequests,
+		MaxRetries:            constants.MaxRetries,
+		InitialBackoff:        Duration(constants.InitalBackoff),
+		BackoffFactor:         constants.BackoffFactor,
+		HTTPTimeout:           Duration(constants.HttpTimeout),
+		TLSHandshakeTimeout:   Duration(constants.TLSHandshakeTimeout),
+		ResponseHeaderTimeout: Duration(constants.ResponseHeaderTimeout),
+		IdleConnTimeout:       Duration(constants.IdleConnTimeout),
+		IncludeComments:       true,
+		FilenamePattern:       PatternProvider + "_" + PatternExam,
+	}
+}
+
+// SearchPaths lists where Load looks for a config file, in order.
+func SearchPaths() []string {
+	paths := []string{FileName}
+	if dir, err := os.UserConfigDir(); err == nil && strings.TrimSpace(dir) != "" {
+		paths = append(paths, filepath.Join(dir, "examtopics-downloader", FileName))
+	}
+	return paths
+}
+
+// Load builds the effective config: defaults, then the file at path (or the
+// first file found in SearchPaths when path is empty), then environment
+// overrides. The result is validated.
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	if path == "" {
+		for _, candidate := range SearchPaths() {
+			if _, err := os.Stat(candidate); err == nil {
+				path = candidate
+				break
+			}
+		}
+	}
+	if path != "" {
+		if err := cfg.readFile(path); err != nil {
+			return cfg, err
+		}
+	}
+
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return cfg, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) readFile(path string) error {
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config %q: %w", path, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("invalid config %q: %w", path, err)
+	}
+	c.Source = path
+	return nil
+}
+
+// applyEnv overrides fields from EXAMTOPICS_<JSON KEY IN UPPER CASE>.
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	var errs []error
+	get := func(key string) (string, string, bool) {
+		name := EnvPrefix + strings.ToUpper(key)
+		value, ok := lookup(name)
+		return name, strings.TrimSpace(value), ok && strings.TrimSpace(value) != ""
+	}
+
+	setString := func(key string, dst *string) {
+		if _, value, ok := get(key); ok {
+			*dst = value
+		}
+	}
+	setInt := func(key string, dst *int) {
+		if name, value, ok := get(key); ok {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a whole number", name, value))
+				return
+			}
+			*dst = n
+		}
+	}
+	setFloat := func(key string, dst *float64) {
+		if name, value, ok := get(key); ok {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a number", name, value))
+				return
+			}
+			*dst = f
+		}
+	}
+	setDuration := func(key string, dst *Duration) {
+		if name, value, ok := get(key); ok {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a duration such as \"1s\"", name, value))
+				return
+			}
+			*dst = Duration(d)
+		}
+	}
+	setList := func(key string, dst *[]string) {
+		if _, value, ok := get(key); ok {
+			var list []string
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+			*dst = list
+		}
+	}
+	setBool := func(key string, dst *bool) {
+		if name, value, ok := get(key); ok {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not true or false", name, value))
+				return
+			}
+			*dst = b
+		}
+	}
+
+	setString("base_url", &c.BaseURL)
+	setFloat("requests_per_second", &c.RequestsPerSecond)
+	setInt("max_concurrent_requests", &c.MaxConcurrentRequests)
+	setInt("max_retries", &c.MaxRetries)
+	setDuration("initial_backoff", &c.InitialBackoff)
+	setFloat("backoff_factor", &c.BackoffFactor)
+	setDuration("http_timeout", &c.HTTPTimeout)
+	setDuration("tls_handshake_timeout", &c.TLSHandshakeTimeout)
+	setDuration("response_header_timeout", &c.ResponseHeaderTimeout)
+	setDuration("idle_conn_timeout", &c.IdleConnTimeout)
+	setList("proxies", &c.Proxies)
+	setString("cookies_file", &c.CookiesFile)
+	setString("header_profile", &c.HeaderProfile)
+	setString("output_dir", &c.OutputDir)
+	setBool("include_comments", &c.IncludeComments)
+	setString("filename_pattern", &c.FilenamePattern)
+
+	return errors.Join(errs...)
+}
+
+// Validate reports every out-of-range value at once, naming the config key.
+func (c Config) Validate() error {
+	var errs []error
+	fail := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+
+	if c.RequestsPerSecond <= 0 || c.RequestsPerSecond > 50 {
+		fail("requests_per_second", "must be above 0 and at most 50, got %g", c.RequestsPerSecond)
+	}
+	if c.MaxConcurrentRequests < 1 || c.MaxConcurrentRequests > 100 {
+		fail("max_concurrent_requests", "must be between 1 and 100, got %d", c.MaxConcurrentRequests)
+	}
+	if c.MaxRetries < 0 || c.MaxRetries > 20 {
+		fail("max_retries", "must be between 0 and 20, got %d", c.MaxRetries)
+	}
+	if c.InitialBackoff <= 0 {
+		fail("initial_backoff", "must be positive, got %s", time.Duration(c.InitialBackoff))
+	}
+	if c.BackoffFactor < 1 {
+		fail("backoff_factor", "must be at least 1, got %g", c.BackoffFactor)
+	}
+	for key, d := range map[string]Duration{
+		"http_timeout":            c.HTTPTimeout,
+		"tls_handshake_timeout":   c.TLSHandshakeTimeout,
+		"response_header_timeout": c.ResponseHeaderTimeout,
+		"idle_conn_timeout":       c.IdleConnTimeout,
+	} {
+		if d <= 0 {
+			fail(key, "must be positive, got %s", time.Duration(d))
+		}
+	}
+
+	for _, proxy := range c.Proxies {
+		if _, err := utils.ParseProxyURL(proxy); err != nil {
+			fail("proxies", "%v", err)
+		}
+	}
+
+	pattern := strings.TrimSpace(c.FilenamePattern)
+	switch {
+	case !strings.Contains(pattern, PatternExam):
+		fail("filename_pattern", "must contain %s so different exams do not overwrite each other, got %q", PatternExam, c.FilenamePattern)
+	case strings.ContainsAny(pattern, `/\`):
+		fail("filename_pattern", "must be a file name without directories (use output_dir), got %q", c.FilenamePattern)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		if c.Source != "" {
+			return fmt.Errorf("invalid config %q:\n%w", c.Source, err)
+		}
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return nil
+}
+
+// OutputBaseName expands FilenamePattern. Provider and exam are expected to
+// be sanitized already.
+func (c Config) OutputBaseName(provider, exam string, now time.Time) string {
+	return strings.NewReplacer(
+		PatternProvider, provider,
+		PatternExam, exam,
+		PatternDate, now.Format("2006-01-02"),
+	).Replace(strings.TrimSpace(c.FilenamePattern))
+}
+
+// datePlaceholderPattern matches {date} with the separator in front of it.
+var datePlaceholderPattern = regexp.MustCompile(`[-_. ]*` + regexp.QuoteMeta(PatternDate))
+
+// StateBaseName is OutputBaseName without the date. The dataset and the
+// checkpoint are named after it, so --incremental and --resume find the
+// files of a run made on another day.
+func (c Config) StateBaseName(provider, exam string) string {
+	pattern := datePlaceholderPattern.ReplaceAllString(strings.TrimSpace(c.FilenamePattern), "")
+	c.FilenamePattern = strings.TrimLeft(pattern, "-_. ")
+	return c.OutputBaseName(provider, exam, time.Time{})
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("writing config: %v", err)
+	}
+	return path
+}
+
+func TestLoadMergesFileAndEnvOverDefaults(t *testing.T) {
+	path := writeConfig(t, `{"requests_per_second": 1.5, "initial_backoff": "250ms", "output_dir": "downloads"}`)
+	t.Setenv("EXAMTOPICS_MAX_RETRIES", "5")
+	t.Setenv("EXAMTOPICS_OUTPUT_DIR", "elsewhere")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.RequestsPerSecond != 1.5 {
+		t.Fatalf("requests_per_second: want 1.5, got %g", cfg.RequestsPerSecond)
+	}
+	if time.Duration(cfg.InitialBackoff) != 250*time.Millisecond {
+		t.Fatalf("initial_backoff: want 250ms, got %s", time.Duration(cfg.InitialBackoff))
+	}
+	if cfg.MaxRetries != 5 {
+		t.Fatalf("max_retries from env: want 5, got %d", cfg.MaxRetries)
+	}
+	if cfg.OutputDir != "elsewhere" {
+		t.Fatalf("env should override the file: want %q, got %q", "elsewhere", cfg.OutputDir)
+	}
+	if cfg.MaxConcurrentRequests != Default().MaxConcurrentRequests || !cfg.IncludeComments {
+		t.Fatal("fields missing from the file should keep their defaults")
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	path := writeConfig(t, `{"max_retrys": 2}`)
+
+	_, err := Load(path)
+	if err == nil || !strings.Contains(err.Error(), `unknown field "max_retrys"`) {
+		t.Fatalf("expected an unknown field error, got %v", err)
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := Default()
+	cfg.RequestsPerSecond = 0
+	cfg.BackoffFactor = 0.5
+	cfg.FilenamePattern = "{provider}"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation to fail")
+	}
+	for _, key := range []string{"requests_per_second", "backoff_factor", "filename_pattern"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Fatalf("expected %s in %q", key, err)
+		}
+	}
+}
+
+func TestProxiesFromEnvAreValidated(t *testing.T) {
+	t.Setenv("EXAMTOPICS_PROXIES", "socks5://127.0.0.1:1080, http://proxy.local:3128")
+
+	cfg, err := Load(writeConfig(t, `{}`))
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(cfg.Proxies) != 2 || cfg.Proxies[1] != "http://proxy.local:3128" {
+		t.Fatalf("proxies from env: got %q", cfg.Proxies)
+	}
+
+	t.Setenv("EXAMTOPICS_PROXIES", "ftp://proxy.local")
+	if _, err := Load(writeConfig(t, `{}`)); err == nil || !strings.Contains(err.Error(), "proxies") {
+		t.Fatalf("expected a proxies error, got %v", err)
+	}
+}
+
+func TestOutputBaseNameExpandsPlaceholders(t *testing.T) {
+	cfg := Default()
+	cfg.FilenamePattern = "{date}-{provider}-{exam}"
+
+	got := cfg.OutputBaseName("cisco", "200-301", time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC))
+	if got != "2025-03-04-cisco-200-301" {
+		t.Fatalf("want %q, got %q", "2025-03-04-cisco-200-301", got)
+	}
+}
+
+func TestStateBaseNameLeavesOutTheDate(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{pattern: "{date}-{provider}-{exam}", want: "cisco-200-301"},
+		{pattern: "{provider}_{date}_{exam}", want: "cisco_200-301"},
+		{pattern: "{exam}.{date}", want: "200-301"},
+		{pattern: "{provider}_{exam}", want: "cisco_200-301"},
+	}
+
+	for _, tc := range tests {
+		cfg := Default()
+		cfg.FilenamePattern = tc.pattern
+		got := cfg.StateBaseName("cisco", "200-301")
+		if got != tc.want {
+			t.Fatalf("%q: want %q, got %q", tc.pattern, tc.want, got)
+		}
+		if html := cfg.OutputBaseName("cisco", "200-301", time.Now()); strings.Contains(tc.pattern, PatternDate) && html == got {
+			t.Fatalf("%q: the HTML name should still carry the date, got %q", tc.pattern, html)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ManifestJob is one exam to download in a batch.
+type ManifestJob struct {
+	Provider string
+	Exam     string
+	// OutDir overrides the batch output directory for this job.
+	OutDir         string
+	Incremental    bool
+	RefreshChanged bool
+	// NoComments leaves comments out of this job's HTML.
+	NoComments bool
+	// Line is where the job starts in the manifest, for error messages.
+	Line int
+}
+
+// LoadManifest reads a batch manifest. Files ending in .yaml or .yml hold a
+// list of entries:
+//
+//	- provider: aws
+//	  exam: saa-c03
+//	  out: downloads/aws
+//	  incremental: true
+//	  comments: false
+//
+// Any other file has one job per line, with optional flags after the exam:
+//
+//	aws saa-c03 out=downloads/aws incremental no-comments
+//
+// Blank lines and lines starting with # are ignored in both formats.
+func LoadManifest(path string) ([]ManifestJob, error) {
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest %q: %w", path, err)
+	}
+
+	var jobs []ManifestJob
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		jobs, err = parseYAMLManifest(payload)
+	default:
+		jobs, err = parseLineManifest(payload)
+	}
+	if err == nil {
+		err = validateManifest(jobs)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid manifest %q: %w", path, err)
+	}
+	return jobs, nil
+}
+
+func parseLineManifest(payload []byte) ([]ManifestJob, error) {
+	var jobs []ManifestJob
+	var errs []error
+
+	scanner := bufio.NewScanner(bytes.NewReader(payload))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		fields := strings.Fields(stripManifestComment(scanner.Text()))
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			errs = append(errs, fmt.Errorf("line %d: want \"<provider> <exam> [options]\"", lineNo))
+			continue
+		}
+
+		job := ManifestJob{Provider: fields[0], Exam: fields[1], Line: lineNo}
+		for _, option := range fields[2:] {
+			key, value, hasValue := strings.Cut(option, "=")
+			if !hasValue {
+				value = "true"
+			}
+			if err := job.set(strings.ReplaceAll(key, "-", "_"), value); err != nil {
+				errs = append(errs, fmt.Errorf("line %d: %w", lineNo, err))
+			}
+		}
+		jobs = append(jobs, job)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return jobs, errors.Join(errs...)
+}
+
+// parseYAMLManifest understands the flat list-of-mappings subset of YAML
+// shown on LoadManifest, which is all a manifest needs.
+func parseYAMLManifest(payload []byte) ([]ManifestJob, error) {
+	var jobs []ManifestJob
+	var errs []error
+	var current *ManifestJob
+
+	scanner := bufio.NewScanner(bytes.NewReader(payload))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		raw := strings.TrimRight(stripManifestComment(scanner.Text()), " \t\r")
+		line := strings.TrimSpace(raw)
+		if line == "" || line == "---" {
+			continue
+		}
+
+		if rest, ok := strings.CutPrefix(line, "-"); ok {
+			jobs = append(jobs, ManifestJob{Line: lineNo})
+			current = &jobs[len(jobs)-1]
+			line = strings.TrimSpace(rest)
+			if line == "" {
+				continue
+			}
+		} else if current == nil || raw[0] != ' ' && raw[0] != '\t' {
+			errs = append(errs, fmt.Errorf("line %d: expected a list entry starting with \"- \"", lineNo))
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			errs = append(errs, fmt.Errorf("line %d: expected \"key: value\"", lineNo))
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		if err := current.set(strings.TrimSpace(key), value); err != nil {
+			errs = append(errs, fmt.Errorf("line %d: %w", lineNo, err))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return jobs, errors.Join(errs...)
+}
+
+func (j *ManifestJob) set(key, value string) error {
+	parseBool := func() (bool, error) {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			switch strings.ToLower(value) {
+			case "yes", "on":
+				return true, nil
+			case "no", "off":
+				return false, nil
+			}
+			return false, fmt.Errorf("%s: %q is not true or false", key, value)
+		}
+		return b, nil
+	}
+
+	var err error
+	switch strings.ToLower(key) {
+	case "provider":
+		j.Provider = value
+	case "exam":
+		j.Exam = value
+	case "out":
+		j.OutDir = value
+	case "incremental":
+		j.Incremental, err = parseBool()
+	case "refresh_changed":
+		j.RefreshChanged, err = parseBool()
+	case "comments":
+		var comments bool
+		comments, err = parseBool()
+		j.NoComments = !comments
+	case "no_comments":
+		j.NoComments, err = parseBool()
+	default:
+		return fmt.Errorf("unknown option %q", key)
+	}
+	return err
+}
+
+func validateManifest(jobs []ManifestJob) error {
+	if len(jobs) == 0 {
+		return fmt.Errorf("no jobs")
+	}
+
+	var errs []error
+	seen := map[string]int{}
+	for i := range jobs {
+		job := &jobs[i]
+		job.Provider = strings.TrimSpace(strings.ToLower(job.Provider))
+		job.Exam = strings.TrimSpace(strings.ToLower(job.Exam))
+		if job.Provider == "" || job.Exam == "" {
+			errs = append(errs, fmt.Errorf("line %d: provider and exam are required", job.Line))
+			continue
+		}
+		if job.RefreshChanged && !job.Incremental {
+			errs = append(errs, fmt.Errorf("line %d: refresh_changed needs incremental", job.Line))
+		}
+
+		key := job.Provider + " " + job.Exam + " " + filepath.Clean(job.OutDir)
+		if first, dup := seen[key]; dup {
+			errs = append(errs, fmt.Errorf("line %d: %s %s is already listed on line %d", job.Line, job.Provider, job.Exam, first))
+			continue
+		}
+		seen[key] = job.Line
+	}
+	return errors.Join(errs...)
+}
+
+// stripManifestComment drops a # comment that starts a line or follows
+// whitespace.
+func stripManifestComment(line string) string {
+	if strings.HasPrefix(strings.TrimSpace(line), "#") {
+		return ""
+	}
+	if i := strings.Index(line, " #"); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
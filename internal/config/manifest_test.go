@@ -0,0 +1,82 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeManifest(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("writing manifest: %v", err)
+	}
+	return path
+}
+
+func TestLoadManifestFormats(t *testing.T) {
+	want := []ManifestJob{
+		{Provider: "aws", Exam: "saa-c03", OutDir: "downloads/aws", Incremental: true, RefreshChanged: true},
+		{Provider: "microsoft", Exam: "az-104", NoComments: true},
+	}
+
+	manifests := map[string]string{
+		"exams.txt": `# certifications for this quarter
+AWS saa-c03 out=downloads/aws incremental refresh-changed
+
+microsoft az-104 no-comments   # no discussion needed
+`,
+		"exams.yaml": `---
+# certifications for this quarter
+- provider: AWS
+  exam: "saa-c03"
+  out: downloads/aws
+  incremental: yes
+  refresh_changed: true
+-
+  provider: microsoft
+  exam: az-104
+  comments: false
+`,
+	}
+	for name, content := range manifests {
+		jobs, err := LoadManifest(writeManifest(t, name, content))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if len(jobs) != len(want) {
+			t.Fatalf("%s: want %d jobs, got %d", name, len(want), len(jobs))
+		}
+		for i := range want {
+			got := jobs[i]
+			got.Line = 0
+			if got != want[i] {
+				t.Fatalf("%s job %d: want %+v, got %+v", name, i+1, want[i], got)
+			}
+		}
+	}
+}
+
+func TestLoadManifestReportsEveryProblem(t *testing.T) {
+	path := writeManifest(t, "exams.txt", "aws\naws saa-c03 parallel=4\n")
+
+	_, err := LoadManifest(path)
+	if err == nil {
+		t.Fatal("expected the manifest to be rejected")
+	}
+	for _, want := range []string{"line 1:", `unknown option "parallel"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in %q", want, err)
+		}
+	}
+
+	path = writeManifest(t, "exams.txt", "aws saa-c03\naws saa-c03\ngoogle pca refresh-changed\n")
+	_, err = LoadManifest(path)
+	for _, want := range []string{"already listed on line 1", "refresh_changed needs incremental"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected %q in %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package fetch
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ErrBlockedPage is wrapped by every FetchError for a page that came back
+// with status 200 but carried a challenge, a login wall or no content.
+var ErrBlockedPage = errors.New("blocked page")
+
+// challengeElementSelector matches the elements Cloudflare puts on its
+// interstitial pages. Words such as "turnstile" or "recaptcha" are not
+// enough: question pages talk about turnstiles and load reCAPTCHA for the
+// comment form.
+const challengeElementSelector = "#challenge-form, #challenge-running, #challenge-stage, #challenge-body-text, #cf-challenge-running, #cf-please-wait, .cf-browser-verification"
+
+// captchaWidgetSelector matches captcha widgets. They only mark a challenge
+// page when they are not part of a form of the site, such as the comment
+// form.
+const captchaWidgetSelector = ".g-recaptcha, .h-captcha, .cf-turnstile"
+
+var (
+	challengeTitlePattern = regexp.MustCompile(`(?i)just a moment|attention required|checking your browser|access denied|are you a robot|verify you are human`)
+	loginTitlePattern     = regexp.MustCompile(`(?i)\b(log ?in|sign ?in)\b`)
+)
+
+// minShellTextLength is the visible text below which a page without links is
+// considered an empty shell, e.g. a JavaScript loader.
+const minShellTextLength = 20
+
+// detectBlockedPage reports whether doc is a challenge page, a login wall or
+// an empty shell rather than real content. It returns an empty kind for
+// usable pages.
+func detectBlockedPage(doc *goquery.Document) (FetchErrorKind, string) {
+	title := strings.TrimSpace(doc.Find("title").First().Text())
+
+	if challengeTitlePattern.MatchString(title) {
+		return ErrorKindAntiBot, "challenge page: " + title
+	}
+	if doc.Find(challengeElementSelector).Length() > 0 {
+		return ErrorKindAntiBot, "challenge page: found challenge form"
+	}
+	if script := challengeScript(doc); script != "" {
+		return ErrorKindAntiBot, "challenge page: found " + script
+	}
+	if doc.Find(captchaWidgetSelector).FilterFunction(func(_ int, s *goquery.Selection) bool {
+		return s.Closest("form").Length() == 0
+	}).Length() > 0 {
+		return ErrorKindAntiBot, "challenge page: found captcha widget"
+	}
+
+	// The site has a login modal on every page, so a password field alone
+	// does not make a login wall; the page itself must be the login page.
+	if loginTitlePattern.MatchString(title) && doc.Find("input[type=password]").Length() > 0 {
+		return ErrorKindLoginWall, "login wall: " + title
+	}
+
+	body := doc.Find("body").Clone()
+	body.Find("script, style, noscript, template").Remove()
+	if len(strings.TrimSpace(body.Text())) < minShellTextLength && doc.Find("a[href]").Length() == 0 {
+		return ErrorKindEmptyPage, "page has no content"
+	}
+
+	return "", ""
+}
+
+// challengeScript returns the marker of a Cloudflare challenge script on the
+// page, or "" when there is none. The Turnstile widget API is also served
+// from challenges.cloudflare.com and is left out.
+func challengeScript(doc *goquery.Document) string {
+	marker := ""
+	doc.Find("script").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		src := strings.ToLower(s.AttrOr("src", ""))
+		switch {
+		case strings.Contains(src, "/cdn-cgi/challenge-platform/"):
+			marker = "/cdn-cgi/challenge-platform/"
+		case strings.Contains(src, "challenges.cloudflare.com") && !strings.Contains(src, "/turnstile/"):
+			marker = "challenges.cloudflare.com"
+		case strings.Contains(s.Text(), "_cf_chl_opt"):
+			marker = "_cf_chl_opt"
+		}
+		return marker == ""
+	})
+	return marker
+}
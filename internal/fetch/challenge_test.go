@@ -0,0 +1,149 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestDetectBlockedPage(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want FetchErrorKind
+	}{
+		{
+			name: "cloudflare interstitial",
+			html: `<html><head><title>Just a moment...</title></head><body><div id="challenge-running">Checking</div></body></html>`,
+			want: ErrorKindAntiBot,
+		},
+		{
+			name: "captcha widget",
+			html: `<html><head><title>ExamTopics</title></head><body><p>Please complete the check below to continue.</p><div class="g-recaptcha" data-sitekey="x"></div></body></html>`,
+			want: ErrorKindAntiBot,
+		},
+		{
+			name: "challenge script",
+			html: `<html><head><title>ExamTopics</title></head><body><p>One more step before you continue.</p><script src="/cdn-cgi/challenge-platform/h/g/orchestrate/chl_page/v1"></script></body></html>`,
+			want: ErrorKindAntiBot,
+		},
+		{
+			name: "question about turnstiles with a captcha on the comment form",
+			html: `<html><head><title>Exam SY0-701 topic 1 question 9 discussion - ExamTopics</title>
+<script src="https://www.google.com/recaptcha/api.js" async defer></script></head>
+<body><p class="card-text">Which physical control stops tailgating? A turnstile or a mantrap.</p>
+<form id="comment-form"><textarea name="comment"></textarea><div class="g-recaptcha" data-sitekey="x"></div></form></body></html>`,
+			want: "",
+		},
+		{
+			name: "login wall",
+			html: `<html><head><title>Log In - ExamTopics</title></head><body><form><input name="email"><input type="password" name="password"></form><a href="/reset/">Forgot password?</a></body></html>`,
+			want: ErrorKindLoginWall,
+		},
+		{
+			name: "javascript shell",
+			html: `<html><head><title>ExamTopics</title><script src="/app.js"></script></head><body><div id="root"></div><noscript>Enable JavaScript to continue using this site.</noscript></body></html>`,
+			want: ErrorKindEmptyPage,
+		},
+		{
+			name: "content page with login modal",
+			html: `<html><head><title>Exam 200-301 topic 1 question 1 discussion - ExamTopics</title></head><body><h1>Question 1</h1><p class="card-text">Which protocol operates at the transport layer?</p><div class="modal"><input type="password"></div></body></html>`,
+			want: "",
+		},
+	}
+
+	for _, tc := range tests {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(tc.html))
+		if err != nil {
+			t.Fatalf("%s: parse: %v", tc.name, err)
+		}
+		if got, reason := detectBlockedPage(doc); got != tc.want {
+			t.Fatalf("%s: want kind %q, got %q (%s)", tc.name, tc.want, got, reason)
+		}
+	}
+}
+
+func TestParseHTMLRetriesChallengeAndDropsItFromCache(t *testing.T) {
+	useTempHTTPCache(t, CacheDefault)
+	useTuning(t, fastTuning())
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Write([]byte(`<html><head><title>Just a moment...</title></head><body></body></html>`))
+			return
+		}
+		w.Write([]byte(`<html><body><p class="card-text">Which protocol operates at the transport layer?</p></body></html>`))
+	}))
+	defer server.Close()
+	useFetcher(t, NewHTTPFetcher(server.Client()))
+
+	url := server.URL + "/discussions/cisco/view/1-exam-200-301-topic-1-question-1-discussion/"
+	doc, err := parseHTMLExpecting(context.Background(), url, questionPageSelector)
+	if err != nil {
+		t.Fatalf("expected the retry to succeed, got %v", err)
+	}
+	if got := strings.TrimSpace(doc.Find(".card-text").Text()); got != "Which protocol operates at the transport layer?" {
+		t.Fatalf("want the real page, got %q", got)
+	}
+	if entry, ok := httpCache.load(url); !ok || strings.Contains(string(entry.Body), "Just a moment") {
+		t.Fatal("expected the cache to hold the real page, not the challenge")
+	}
+	if got := limiter.Stats().BlockedPages; got != 1 {
+		t.Fatalf("blocked pages: want 1, got %d", got)
+	}
+}
+
+func TestParseHTMLReportsPersistentEmptyPage(t *testing.T) {
+	useTuning(t, fastTuning())
+	url := "https://www.examtopics.com/discussions/cisco/view/1-exam-200-301-topic-1-question-1-discussion/"
+	useFetcher(t, stubFetcher{url: `<html><body><h1>Question</h1><a href="/">Home</a></body></html>`})
+
+	_, err := parseHTMLExpecting(context.Background(), url, questionPageSelector)
+	if !errors.Is(err, ErrBlockedPage) || ErrorKind(err) != ErrorKindEmptyPage {
+		t.Fatalf("expected an empty page error, got %v", err)
+	}
+}
+
+func TestParseHTMLKeepsPageMentioningTurnstiles(t *testing.T) {
+	useTuning(t, fastTuning())
+	url := "https://www.examtopics.com/discussions/comptia/view/9-exam-sy0-701-topic-1-question-9-discussion/"
+	useFetcher(t, stubFetcher{url: `<html><head><title>Exam SY0-701 topic 1 question 9 discussion - ExamTopics</title>
+<script src="https://www.google.com/recaptcha/api.js"></script></head>
+<body><p class="card-text">Which physical control stops tailgating? A turnstile.</p>
+<form id="comment-form"><div class="g-recaptcha" data-sitekey="x"></div></form></body></html>`})
+
+	doc, err := parseHTMLExpecting(context.Background(), url, questionPageSelector)
+	if err != nil {
+		t.Fatalf("expected the question page to parse, got %v", err)
+	}
+	if got := strings.TrimSpace(doc.Find(".card-text").Text()); got != "Which physical control stops tailgating? A turnstile." {
+		t.Fatalf("unexpected question text %q", got)
+	}
+	if got := limiter.Stats().BlockedPages; got != 0 {
+		t.Fatalf("blocked pages: want 0, got %d", got)
+	}
+}
+
+func TestParseHTMLKeepsBlockedPageInCacheOnlyMode(t *testing.T) {
+	useTempHTTPCache(t, CacheOnly)
+	useTuning(t, fastTuning())
+	useFetcher(t, NewHTTPFetcher(nil))
+
+	url := "https://www.examtopics.com/discussions/cisco/view/1-exam-200-301-topic-1-question-1-discussion/"
+	httpCache.store(&cachedResponse{URL: url, FetchedAt: time.Now(), Body: []byte(`<html><head><title>Just a moment...</title></head><body></body></html>`)})
+
+	if _, err := parseHTMLExpecting(context.Background(), url, questionPageSelector); ErrorKind(err) != ErrorKindAntiBot {
+		t.Fatalf("expected an anti-bot error, got %v", err)
+	}
+	if _, ok := httpCache.load(url); !ok {
+		t.Fatal("--cache-only must not delete the only copy of a page")
+	}
+}
@@ -0,0 +1,152 @@
+package fetch
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"examtopics-downloader/internal/models"
+)
+
+// Checkpoint is an append-only JSONL journal of finished questions keyed by
+// discussion link. Each line is written as soon as a question page has been
+// scraped, so an interrupted extraction can resume without refetching it.
+type Checkpoint struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	entries map[string]models.QuestionData
+}
+
+type checkpointEntry struct {
+	Link string              `json:"link"`
+	Data models.QuestionData `json:"data"`
+}
+
+// OpenCheckpoint opens the journal at path. With resume set, entries from an
+// earlier run are loaded and kept; otherwise the journal starts out empty.
+func OpenCheckpoint(path string, resume bool) (*Checkpoint, error) {
+	entries := map[string]models.QuestionData{}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+
+	if resume {
+		loaded, err := loadCheckpointEntries(path)
+		if err != nil {
+			return nil, err
+		}
+		entries = loaded
+	} else {
+		flags |= os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open checkpoint %q: %w", path, err)
+	}
+
+	return &Checkpoint{path: path, file: file, entries: entries}, nil
+}
+
+func loadCheckpointEntries(path string) (map[string]models.QuestionData, error) {
+	entries := map[string]models.QuestionData{}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint %q: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		// A crash can leave the last line half-written; skip anything that
+		// does not decode instead of failing the whole resume.
+		var entry checkpointEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			debugf("skipping unreadable checkpoint line in %q: %v", path, err)
+			continue
+		}
+		if entry.Link == "" {
+			continue
+		}
+		entries[entry.Link] = entry.Data
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint %q: %w", path, err)
+	}
+
+	return entries, nil
+}
+
+func (c *Checkpoint) Path() string {
+	return c.path
+}
+
+// Len reports how many finished questions the journal holds.
+func (c *Checkpoint) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+func (c *Checkpoint) Lookup(link string) (models.QuestionData, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data, ok := c.entries[link]
+	return data, ok
+}
+
+// Record appends a finished question to the journal.
+func (c *Checkpoint) Record(link string, data models.QuestionData) error {
+	payload, err := json.Marshal(checkpointEntry{Link: link, Data: data})
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint entry: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.file == nil {
+		return fmt.Errorf("checkpoint %q is closed", c.path)
+	}
+	if _, err := c.file.Write(append(payload, '\n')); err != nil {
+		return fmt.Errorf("failed to write checkpoint %q: %w", c.path, err)
+	}
+	c.entries[link] = data
+	return nil
+}
+
+func (c *Checkpoint) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.file == nil {
+		return nil
+	}
+	err := c.file.Close()
+	c.file = nil
+	return err
+}
+
+// Remove closes and deletes the journal once its output has been written.
+func (c *Checkpoint) Remove() error {
+	if err := c.Close(); err != nil {
+		return err
+	}
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package fetch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"examtopics-downloader/internal/models"
+)
+
+func TestCheckpointResumeSkipsTruncatedLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "exam.checkpoint.jsonl")
+
+	checkpoint, err := OpenCheckpoint(path, false)
+	if err != nil {
+		t.Fatalf("OpenCheckpoint failed: %v", err)
+	}
+	link := "/discussions/cisco/view/1-exam-200-301-topic-1-question-1-discussion/"
+	if err := checkpoint.Record(link, models.QuestionData{Title: "q1", Answer: "B"}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if err := checkpoint.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Simulate a crash in the middle of writing the next entry.
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatalf("failed reopening journal: %v", err)
+	}
+	if _, err := file.WriteString(`{"link":"/discussions/cisco/view/2-exam`); err != nil {
+		t.Fatalf("failed appending partial line: %v", err)
+	}
+	file.Close()
+
+	resumed, err := OpenCheckpoint(path, true)
+	if err != nil {
+		t.Fatalf("OpenCheckpoint(resume) failed: %v", err)
+	}
+	defer resumed.Close()
+
+	if resumed.Len() != 1 {
+		t.Fatalf("expected 1 resumed entry, got %d", resumed.Len())
+	}
+	data, ok := resumed.Lookup(link)
+	if !ok || data.Title != "q1" || data.Answer != "B" {
+		t.Fatalf("unexpected resumed entry: %#v (found=%v)", data, ok)
+	}
+}
+
+func TestCheckpointWithoutResumeStartsEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "exam.checkpoint.jsonl")
+	if err := os.WriteFile(path, []byte(`{"link":"/x","data":{"title":"old"}}`+"\n"), 0o644); err != nil {
+		t.Fatalf("failed writing fixture: %v", err)
+	}
+
+	checkpoint, err := OpenCheckpoint(path, false)
+	if err != nil {
+		t.Fatalf("OpenCheckpoint failed: %v", err)
+	}
+	if checkpoint.Len() != 0 {
+		t.Fatalf("expected empty checkpoint, got %d entries", checkpoint.Len())
+	}
+	if err := checkpoint.Remove(); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected journal to be deleted, stat err = %v", err)
+	}
+}
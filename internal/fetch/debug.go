@@ -1,15 +1,35 @@
 package fetch
 
-import "log"
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
 
 var debugLogsEnabled bool
 
+var statusOutput io.Writer = os.Stdout
+
 func SetDebug(enabled bool) {
 	debugLogsEnabled = enabled
 }
 
+// SetOutput redirects the status lines printed during extraction, e.g. to
+// keep stdout clean for machine-readable output.
+func SetOutput(w io.Writer) {
+	if w == nil {
+		w = io.Discard
+	}
+	statusOutput = w
+}
+
 func debugf(format string, args ...any) {
 	if debugLogsEnabled {
 		log.Printf(format, args...)
 	}
 }
+
+func statusf(format string, args ...any) {
+	fmt.Fprintf(statusOutput, format, args...)
+}
@@ -0,0 +1,95 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"examtopics-downloader/internal/models"
+)
+
+// FetchErrorKind classifies why a page could not be fetched.
+type FetchErrorKind string
+
+const (
+	// ErrorKindHTTPStatus is a response with an unexpected status code.
+	ErrorKindHTTPStatus FetchErrorKind = "http_status"
+	// ErrorKindNetwork covers connection, timeout and read failures.
+	ErrorKindNetwork FetchErrorKind = "network"
+	// ErrorKindParse is a body that could not be parsed as a usable page.
+	ErrorKindParse FetchErrorKind = "parse"
+	// ErrorKindAntiBot is a challenge or block page served instead of content.
+	ErrorKindAntiBot FetchErrorKind = "anti_bot"
+	// ErrorKindLoginWall is a login page served instead of content.
+	ErrorKindLoginWall FetchErrorKind = "login_wall"
+	// ErrorKindEmptyPage is a page without the content it should carry.
+	ErrorKindEmptyPage FetchErrorKind = "empty_page"
+	// ErrorKindNotCached is a cache miss in CacheOnly mode.
+	ErrorKindNotCached FetchErrorKind = "not_cached"
+	// ErrorKindCancelled means the context ended before the page arrived.
+	ErrorKindCancelled FetchErrorKind = "cancelled"
+)
+
+// Page types reported in failures.
+const (
+	pageQuestion       = "question"
+	pageDiscussionList = "discussion_list"
+)
+
+// FetchError describes a failed page fetch.
+type FetchError struct {
+	URL        string
+	Kind       FetchErrorKind
+	StatusCode int
+	Err        error
+}
+
+func (e *FetchError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s: %s (status %d): %v", e.URL, e.Kind, e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("%s: %s: %v", e.URL, e.Kind, e.Err)
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+func newFetchError(url string, kind FetchErrorKind, status int, err error) *FetchError {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		kind = ErrorKindCancelled
+	}
+	return &FetchError{URL: url, Kind: kind, StatusCode: status, Err: err}
+}
+
+// ErrorKind returns the kind of a FetchError anywhere in err's chain, or
+// ErrorKindNetwork for errors from other Fetcher implementations.
+func ErrorKind(err error) FetchErrorKind {
+	var fetchErr *FetchError
+	if errors.As(err, &fetchErr) {
+		return fetchErr.Kind
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrorKindCancelled
+	}
+	return ErrorKindNetwork
+}
+
+// newFailure turns a fetch error into a report entry.
+func newFailure(page, url string, err error) models.FetchFailure {
+	failure := models.FetchFailure{
+		URL:    url,
+		Page:   page,
+		Kind:   string(ErrorKind(err)),
+		Reason: err.Error(),
+	}
+
+	var fetchErr *FetchError
+	if errors.As(err, &fetchErr) {
+		failure.Status = fetchErr.StatusCode
+		if fetchErr.Err != nil {
+			failure.Reason = fetchErr.Err.Error()
+		}
+	}
+	return failure
+}
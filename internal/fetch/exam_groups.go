@@ -0,0 +1,53 @@
+package fetch
+
+import (
+	"sort"
+	"strings"
+
+	"examtopics-downloader/internal/models"
+)
+
+// UngroupedExamSlug collects questions whose discussion link names no exam.
+const UngroupedExamSlug = "other"
+
+// ExamGroup is the questions of one exam of a provider-wide extraction.
+type ExamGroup struct {
+	Slug      string
+	Questions []models.QuestionData
+}
+
+// ExamSlugForLink returns the exam a discussion link belongs to, with
+// version variants collapsed the same way exam selection matches them, or ""
+// when the link names no exam.
+func ExamSlugForLink(providerName, link string) string {
+	return normalizeExamSlug(providerName, extractExamSlugFromDiscussionURL(link))
+}
+
+// GroupQuestionsByExam partitions the questions of a provider-wide
+// extraction by their normalized ExamSlug, or ExamSlugForLink for questions
+// saved without one. Groups are sorted by slug and keep the question order
+// within each exam; questions without an exam end up in UngroupedExamSlug.
+func GroupQuestionsByExam(providerName string, questions []models.QuestionData) []ExamGroup {
+	providerName = strings.TrimSpace(strings.ToLower(providerName))
+	index := map[string]int{}
+	var groups []ExamGroup
+	for _, question := range questions {
+		slug := normalizeExamSlug(providerName, question.ExamSlug)
+		if slug == "" {
+			slug = ExamSlugForLink(providerName, question.QuestionLink)
+		}
+		if slug == "" {
+			slug = UngroupedExamSlug
+		}
+		i, ok := index[slug]
+		if !ok {
+			i = len(groups)
+			index[slug] = i
+			groups = append(groups, ExamGroup{Slug: slug})
+		}
+		groups[i].Questions = append(groups[i].Questions, question)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Slug < groups[j].Slug })
+	return groups
+}
@@ -0,0 +1,46 @@
+package fetch
+
+import (
+	"testing"
+
+	"examtopics-downloader/internal/models"
+)
+
+func TestGroupQuestionsByExamCollapsesVariants(t *testing.T) {
+	link := func(path string) models.QuestionData {
+		return models.QuestionData{QuestionLink: "https://www.examtopics.com/discussions/oracle/view/" + path}
+	}
+	questions := []models.QuestionData{
+		link("1-exam-1z0-1042-22-topic-1-question-2-discussion/"),
+		link("2-exam-1z0-082-topic-1-question-1-discussion/"),
+		link("3-exam-1z0-1042-20-topic-1-question-1-discussion/"),
+		link("4-topic-1-question-9-discussion/"),
+	}
+
+	groups := GroupQuestionsByExam("oracle", questions)
+
+	want := []struct {
+		slug  string
+		links []string
+	}{
+		{slug: "1z0-082", links: []string{questions[1].QuestionLink}},
+		{slug: "1z0-1042", links: []string{questions[0].QuestionLink, questions[2].QuestionLink}},
+		{slug: UngroupedExamSlug, links: []string{questions[3].QuestionLink}},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("want %d groups, got %d: %+v", len(want), len(groups), groups)
+	}
+	for i, w := range want {
+		if groups[i].Slug != w.slug {
+			t.Fatalf("group %d: want slug %q, got %q", i, w.slug, groups[i].Slug)
+		}
+		if len(groups[i].Questions) != len(w.links) {
+			t.Fatalf("group %q: want %d questions, got %d", w.slug, len(w.links), len(groups[i].Questions))
+		}
+		for j, l := range w.links {
+			if groups[i].Questions[j].QuestionLink != l {
+				t.Fatalf("group %q question %d: want %q, got %q", w.slug, j, l, groups[i].Questions[j].QuestionLink)
+			}
+		}
+	}
+}
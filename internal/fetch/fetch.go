@@ -2,9 +2,9 @@ package fetch
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"net/url"
 	"regexp"
 	"sort"
@@ -12,14 +12,11 @@ import (
 	"strings"
 	"time"
 
-	"examtopics-downloader/internal/constants"
 	"examtopics-downloader/internal/utils"
 
 	"github.com/PuerkitoBio/goquery"
 )
 
-var client = utils.NewHTTPClient()
-
 var (
 	providerHrefPattern           = regexp.MustCompile(`(?i)^/exams/([a-z0-9-]+)/?$`)
 	discussionProviderHrefPattern = regexp.MustCompile(`(?i)^/discussions/([a-z0-9-]+)/?$`)
@@ -31,77 +28,82 @@ var (
 	trailingVersionTokenPattern   = regexp.MustCompile(`(?i)^(?:\d{2}|\d{4}|v\d+|ver\d+|rev\d+)$`)
 )
 
-func FetchURL(url string, client http.Client) []byte {
-	backoff := constants.InitalBackoff
+// ParseHTML fetches url and parses it. Challenge pages, login walls and
+// empty shells are not returned as content: they are dropped from the cache,
+// slow the shared limiter down and are retried with backoff.
+func ParseHTML(ctx context.Context, url string) (*goquery.Document, error) {
+	return parseHTMLExpecting(ctx, url, "")
+}
+
+// parseHTMLExpecting is ParseHTML for pages that must contain an element
+// matching selector; a page without one is retried as an empty page.
+func parseHTMLExpecting(ctx context.Context, url, selector string) (*goquery.Document, error) {
+	backoff := tuning.InitialBackoff
+	var blockedErr *FetchError
 
-	for attempt := 0; attempt <= constants.MaxRetries; attempt++ {
+	for attempt := 0; attempt <= tuning.MaxRetries; attempt++ {
 		if attempt > 0 {
 			delay := utils.DelayTime(backoff)
-			debugf("Retry attempt %d for URL: %s after waiting %v", attempt, url, delay)
-			utils.Sleep(delay)
-			backoff = utils.BackoffTime(backoff, constants.BackoffFactor)
+			debugf("Retry attempt %d for blocked page %s after waiting %v", attempt, url, delay)
+			if err := utils.SleepContext(ctx, delay); err != nil {
+				return nil, newFetchError(url, ErrorKindCancelled, 0, err)
+			}
+			backoff = utils.BackoffTime(backoff, tuning.BackoffFactor)
 		}
 
-		req, err := http.NewRequest(http.MethodGet, url, nil)
+		body, fromCache, err := fetchPage(ctx, fetcher, url)
 		if err != nil {
-			debugf("failed to create request for URL %s: %v", url, err)
-			continue
+			var fetchErr *FetchError
+			if errors.As(err, &fetchErr) {
+				return nil, err
+			}
+			return nil, newFetchError(url, ErrorKindNetwork, 0, err)
 		}
-		// Reduce anti-bot 403s by mimicking a normal browser request.
-		req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/132.0.0.0 Safari/537.36")
-		req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
-		req.Header.Set("Accept-Language", "en-US,en;q=0.9")
-		req.Header.Set("Referer", "https://www.examtopics.com/")
 
-		resp, err := client.Do(req)
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
 		if err != nil {
-			debugf("failed to fetch URL (attempt %d): %v", attempt, err)
-			continue
+			return nil, newFetchError(url, ErrorKindParse, 0, fmt.Errorf("failed to parse HTML: %w", err))
 		}
 
-		if resp.StatusCode == http.StatusOK {
-			body, err := io.ReadAll(resp.Body)
-			resp.Body.Close()
-			if err != nil {
-				debugf("failed to read response body: %v", err)
-				return nil
-			}
-			return body
+		kind, reason := detectBlockedPage(doc)
+		if kind == "" && selector != "" && doc.Find(selector).Length() == 0 {
+			kind, reason = ErrorKindEmptyPage, "page has no "+selector+" content"
 		}
-		resp.Body.Close()
-
-		if resp.StatusCode != http.StatusServiceUnavailable {
-			debugf("request failed with status code: %d", resp.StatusCode)
-			return nil
+		// A cached page may predate the session, so only pages the server
+		// just sent tell whether the session is still valid.
+		if !fromCache {
+			checkSession(doc, kind, url)
+		}
+		if kind == "" {
+			return doc, nil
 		}
-	}
-
-	debugf("exhausted retries for URL: %s", url)
-	return nil
-}
-
-func ParseHTML(url string, client http.Client) (*goquery.Document, error) {
-	body := FetchURL(url, client)
-	if body == nil {
-		return nil, fmt.Errorf("empty response body from URL %q", url)
-	}
 
-	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse HTML from URL %q: %w", url, err)
+		debugf("blocked page at %s: %s", url, reason)
+		blockedErr = newFetchError(url, kind, 0, fmt.Errorf("%w: %s", ErrBlockedPage, reason))
+		if cacheMode == CacheOnly {
+			// The cached copy is the only one there is: keep it.
+			break
+		}
+		httpCache.remove(url)
+		limiter.Blocked()
 	}
 
-	return doc, nil
+	return nil, blockedErr
 }
 
 // Fetches total number of pages
-func getMaxNumPages(url string) int {
-	doc, err := ParseHTML(url, *client)
+func getMaxNumPages(ctx context.Context, url string) int {
+	doc, err := ParseHTML(ctx, url)
 	if err != nil {
 		debugf("failed parsing HTML for number of pages: %v", err)
 		return 1
 	}
+	return pageCountFromDoc(doc)
+}
 
+// pageCountFromDoc reads "Page x of N" from a discussion list page, or 1
+// when the page has no indicator.
+func pageCountFromDoc(doc *goquery.Document) int {
 	var pageCount int
 	doc.Find(".discussion-list-page-indicator strong").Each(func(i int, s *goquery.Selection) {
 		if i == 1 {
@@ -117,11 +119,11 @@ func getMaxNumPages(url string) int {
 	return pageCount
 }
 
-func GetAllProviders() []string {
+func GetAllProviders(ctx context.Context) []string {
 	seen := map[string]struct{}{}
 	providers := make([]string, 0, 64)
 
-	for _, provider := range getProvidersFromExams() {
+	for _, provider := range getProvidersFromExams(ctx) {
 		if _, exists := seen[provider]; exists {
 			continue
 		}
@@ -129,7 +131,7 @@ func GetAllProviders() []string {
 		providers = append(providers, provider)
 	}
 
-	for _, provider := range getProvidersFromDiscussions() {
+	for _, provider := range getProvidersFromDiscussions(ctx) {
 		if _, exists := seen[provider]; exists {
 			continue
 		}
@@ -141,8 +143,8 @@ func GetAllProviders() []string {
 	return providers
 }
 
-func getProvidersFromExams() []string {
-	doc, err := ParseHTML("https://www.examtopics.com/exams/", *client)
+func getProvidersFromExams(ctx context.Context) []string {
+	doc, err := ParseHTML(ctx, utils.AddToBaseUrl("/exams/"))
 	if err != nil {
 		debugf("failed to parse HTML for providers from exams: %v", err)
 		return nil
@@ -150,7 +152,7 @@ func getProvidersFromExams() []string {
 	return extractProvidersFromExamsDoc(doc)
 }
 
-func getProvidersFromDiscussions() []string {
+func getProvidersFromDiscussions(ctx context.Context) []string {
 	const (
 		maxAttempts                = 3
 		minLikelyGoodProviderCount = 150
@@ -160,7 +162,7 @@ func getProvidersFromDiscussions() []string {
 	expectedCategories := 0
 
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
-		doc, err := ParseHTML("https://www.examtopics.com/discussions/", *client)
+		doc, err := ParseHTML(ctx, utils.AddToBaseUrl("/discussions/"))
 		if err != nil {
 			debugf("failed to parse HTML for providers from discussions (attempt %d/%d): %v", attempt, maxAttempts, err)
 			continue
@@ -187,7 +189,9 @@ func getProvidersFromDiscussions() []string {
 		}
 
 		if attempt < maxAttempts {
-			time.Sleep(600 * time.Millisecond)
+			if err := utils.SleepContext(ctx, 600*time.Millisecond); err != nil {
+				break
+			}
 		}
 	}
 
@@ -330,10 +334,10 @@ func extractDiscussionCategoryCount(doc *goquery.Document) int {
 	return count
 }
 
-func GetProviderExams(providerName string) []string {
+func GetProviderExams(ctx context.Context, providerName string) []string {
 	providerName = strings.TrimSpace(strings.ToLower(providerName))
-	baseURL := fmt.Sprintf("https://www.examtopics.com/exams/%s/", providerName)
-	doc, err := ParseHTML(baseURL, *client)
+	baseURL := utils.AddToBaseUrl(fmt.Sprintf("/exams/%s/", providerName))
+	doc, err := ParseHTML(ctx, baseURL)
 	if err != nil {
 		debugf("failed to parse HTML for provider exams: %v", err)
 		return nil
@@ -372,15 +376,42 @@ func GetProviderExams(providerName string) []string {
 	return allExams
 }
 
-func GetProviderExamSlugs(providerName string) []string {
+// Sources reported by GetProviderExamOptions.
+const (
+	ExamSourceExams       = "exams"
+	ExamSourceDiscussions = "discussions"
+	ExamSourceFallback    = "fallback"
+)
+
+// ExamOption is a selectable exam slug together with where it was found:
+// the provider's /exams/ listing, or inferred from discussion links.
+type ExamOption struct {
+	Slug   string `json:"slug"`
+	Source string `json:"source"`
+}
+
+func GetProviderExamSlugs(ctx context.Context, providerName string) []string {
+	options := GetProviderExamOptions(ctx, providerName)
+	if len(options) == 0 {
+		return nil
+	}
+
+	examSlugs := make([]string, 0, len(options))
+	for _, option := range options {
+		examSlugs = append(examSlugs, option.Slug)
+	}
+	return examSlugs
+}
+
+func GetProviderExamOptions(ctx context.Context, providerName string) []ExamOption {
 	providerName = strings.TrimSpace(strings.ToLower(providerName))
 	if providerName == "" {
 		return nil
 	}
 
 	seen := map[string]struct{}{}
-	examSlugs := make([]string, 0, 32)
-	add := func(raw string) {
+	options := make([]ExamOption, 0, 32)
+	add := func(raw, source string) {
 		normalized := normalizeExamSlug(providerName, raw)
 		if normalized == "" {
 			return
@@ -389,29 +420,31 @@ func GetProviderExamSlugs(providerName string) []string {
 			return
 		}
 		seen[normalized] = struct{}{}
-		examSlugs = append(examSlugs, normalized)
+		options = append(options, ExamOption{Slug: normalized, Source: source})
 	}
 
-	officialExamLinks := GetProviderExams(providerName)
+	officialExamLinks := GetProviderExams(ctx, providerName)
 	officialExamSlugs := extractExamSlugsFromExamLinks(providerName, officialExamLinks)
 	for _, exam := range officialExamSlugs {
-		add(exam)
+		add(exam, ExamSourceExams)
 	}
 
 	// Smart fallback strategy for providers missing /exams/ coverage:
 	// infer distinct exam slugs from provider discussion links.
-	inferredFromDiscussions := inferExamSlugsFromDiscussionPages(providerName)
+	inferredFromDiscussions := inferExamSlugsFromDiscussionPages(ctx, providerName)
 	for _, exam := range inferredFromDiscussions {
-		add(exam)
+		add(exam, ExamSourceDiscussions)
 	}
 
-	sort.Strings(examSlugs)
-	if len(examSlugs) == 0 {
+	sort.Slice(options, func(i, j int) bool {
+		return options[i].Slug < options[j].Slug
+	})
+	if len(options) == 0 {
 		// last-resort fallback to still ingest provider content
-		return []string{"all-discussions"}
+		return []ExamOption{{Slug: "all-discussions", Source: ExamSourceFallback}}
 	}
 
-	return examSlugs
+	return options
 }
 
 func extractExamSlugsFromExamLinks(providerName string, examLinks []string) []string {
@@ -439,40 +472,31 @@ func extractExamSlugsFromExamLinks(providerName string, examLinks []string) []st
 	return out
 }
 
-func inferExamSlugsFromDiscussionPages(providerName string) []string {
+// inferExamSlugsFromDiscussionPages reads exam slugs off the provider's
+// discussion links. The links come from the provider's link index, so a
+// download that follows discovery needs no further list page requests.
+func inferExamSlugsFromDiscussionPages(ctx context.Context, providerName string) []string {
 	providerName = strings.TrimSpace(strings.ToLower(providerName))
 	if providerName == "" {
 		return nil
 	}
 
-	if cached, ok := getCachedDiscussionExamSlugs(providerName); ok {
-		debugf("using cached discussion-derived exams for provider %q (%d)", providerName, len(cached))
-		return cached
-	}
-
+	entries, _ := linkIndexes.providerLinks(ctx, providerName, false, scanProgress{})
 	seen := map[string]struct{}{}
 	out := make([]string, 0, 32)
-	baseURL := fmt.Sprintf("https://www.examtopics.com/discussions/%s/", providerName)
-	numPages := getMaxNumPages(baseURL)
-
-	for pageNum := 1; pageNum <= numPages; pageNum++ {
-		pageURL := fmt.Sprintf("https://www.examtopics.com/discussions/%s/%d", providerName, pageNum)
-		discussionLinks := getDiscussionLinksFromPage(pageURL)
-		for _, link := range discussionLinks {
-			examSlug := extractExamSlugFromDiscussionURL(link)
-			if examSlug == "" {
-				continue
-			}
-			if _, exists := seen[examSlug]; exists {
-				continue
-			}
-			seen[examSlug] = struct{}{}
-			out = append(out, examSlug)
+	for _, entry := range entries {
+		examSlug := extractExamSlugFromDiscussionURL(entry.Link)
+		if examSlug == "" {
+			continue
+		}
+		if _, exists := seen[examSlug]; exists {
+			continue
 		}
+		seen[examSlug] = struct{}{}
+		out = append(out, examSlug)
 	}
 
 	sort.Strings(out)
-	setCachedDiscussionExamSlugs(providerName, out)
 	return out
 }
 
@@ -521,15 +545,18 @@ func extractExamSlugFromDiscussionURL(link string) string {
 	return slug
 }
 
-func getDiscussionLinksFromPage(url string) []string {
-	doc, err := ParseHTML(url, *client)
-	if err != nil {
-		debugf("failed to parse HTML for %s: %v", url, err)
-		return nil
-	}
+// discussionLink is a discussion view link as listed on a provider's
+// discussion pages, with the reply count shown next to it (-1 if unknown).
+type discussionLink struct {
+	Link    string
+	Replies int
+	// ListPage is the discussion list page the link was found on.
+	ListPage string
+}
 
+func extractDiscussionEntries(doc *goquery.Document) []discussionLink {
 	seen := map[string]struct{}{}
-	out := make([]string, 0, 64)
+	out := make([]discussionLink, 0, 64)
 	doc.Find("a").Each(func(i int, s *goquery.Selection) {
 		href, exists := s.Attr("href")
 		if !exists {
@@ -544,7 +571,14 @@ func getDiscussionLinksFromPage(url string) []string {
 			return
 		}
 		seen[clean] = struct{}{}
-		out = append(out, clean)
+
+		replies := -1
+		if countNode := s.Closest(".discussion-row").Find(".discussion-stats-replies").First(); countNode.Length() > 0 {
+			if countText := strings.TrimSpace(countNode.Text()); countText != "" {
+				replies = parseDiscussionCount(countText)
+			}
+		}
+		out = append(out, discussionLink{Link: clean, Replies: replies})
 	})
 
 	return out
@@ -556,18 +590,12 @@ func normalizeDiscussionViewHref(rawHref string) string {
 		return ""
 	}
 
-	switch {
-	case strings.HasPrefix(rawHref, "https://www.examtopics.com/"):
-		rawHref = strings.TrimPrefix(rawHref, "https://www.examtopics.com")
-	case strings.HasPrefix(rawHref, "http://www.examtopics.com/"):
-		rawHref = strings.TrimPrefix(rawHref, "http://www.examtopics.com")
-	case strings.HasPrefix(rawHref, "https://") || strings.HasPrefix(rawHref, "http://"):
+	if strings.HasPrefix(rawHref, "https://") || strings.HasPrefix(rawHref, "http://") {
 		parsed, err := url.Parse(rawHref)
 		if err != nil {
 			return ""
 		}
-		host := strings.TrimSpace(strings.ToLower(parsed.Hostname()))
-		if host != "www.examtopics.com" && host != "examtopics.com" {
+		if !isSiteHost(parsed.Host) {
 			return ""
 		}
 		rawHref = parsed.EscapedPath()
@@ -592,6 +620,17 @@ func normalizeDiscussionViewHref(rawHref string) string {
 	return rawHref
 }
 
+// isSiteHost reports whether an absolute link points at the site being
+// scraped: the configured origin, or ExamTopics itself, since mirrors and
+// recorded pages keep absolute links to the original host.
+func isSiteHost(host string) bool {
+	host = strings.TrimSpace(strings.ToLower(host))
+	if host == strings.ToLower(utils.BaseHost()) {
+		return true
+	}
+	return host == "www.examtopics.com" || host == "examtopics.com"
+}
+
 func matchesExamSelection(providerName, selectedExam, link string) bool {
 	providerName = strings.TrimSpace(strings.ToLower(providerName))
 	selectedExam = strings.TrimSpace(strings.ToLower(selectedExam))
@@ -624,14 +663,3 @@ func matchesExamSelection(providerName, selectedExam, link string) bool {
 	return utils.GrepString(link, selectedExam) || utils.GrepString(link, selectedNormalized)
 }
 
-// Extracts matching links from a single page.
-func getLinksFromPage(providerName, url, selectedExam string) []string {
-	var matchingLinks []string
-	for _, href := range getDiscussionLinksFromPage(url) {
-		if matchesExamSelection(providerName, selectedExam, href) {
-			matchingLinks = append(matchingLinks, href)
-		}
-	}
-
-	return matchingLinks
-}
@@ -0,0 +1,194 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"examtopics-downloader/internal/utils"
+)
+
+// Fetcher retrieves the raw body of a page. Every scraping function in this
+// package goes through it, so callers can plug in a test double, a
+// proxy-configured client or an extra caching layer with SetFetcher.
+type Fetcher interface {
+	Get(ctx context.Context, url string) ([]byte, error)
+}
+
+// ErrNotCached is returned in CacheOnly mode when a URL has no cached copy.
+var ErrNotCached = errors.New("no cached response")
+
+var fetcher Fetcher = NewHTTPFetcher(nil)
+
+// SetFetcher replaces the Fetcher used for all requests. Passing nil restores
+// the default HTTPFetcher.
+func SetFetcher(f Fetcher) {
+	if f == nil {
+		f = NewHTTPFetcher(nil)
+	}
+	fetcher = f
+}
+
+// HTTPFetcher is the default Fetcher. It sends the headers of the selected
+// HeaderProfile with the Referer of the linking page, paces
+// network requests through the shared adaptive limiter, retries 429, 403 and
+// 503 responses and serves pages from the on-disk response cache according
+// to the current CacheMode.
+type HTTPFetcher struct {
+	Client *http.Client
+}
+
+// NewHTTPFetcher wraps client; a nil client gets the tuned default transport
+// and the session cookies set with SetCookieJar.
+func NewHTTPFetcher(client *http.Client) *HTTPFetcher {
+	if client == nil {
+		client = utils.NewHTTPClient()
+		if sessionJar != nil {
+			client.Jar = sessionJar
+		}
+	}
+	return &HTTPFetcher{Client: client}
+}
+
+// cacheReportingFetcher is implemented by Fetchers that can tell whether a
+// body was served from the response cache instead of the network.
+type cacheReportingFetcher interface {
+	getReportingCache(ctx context.Context, url string) (body []byte, fromCache bool, err error)
+}
+
+// fetchPage gets url through f and reports whether the body came from the
+// response cache. Fetchers that cannot tell count as the network.
+func fetchPage(ctx context.Context, f Fetcher, url string) ([]byte, bool, error) {
+	if reporting, ok := f.(cacheReportingFetcher); ok {
+		return reporting.getReportingCache(ctx, url)
+	}
+	body, err := f.Get(ctx, url)
+	return body, false, err
+}
+
+func (f *HTTPFetcher) Get(ctx context.Context, url string) ([]byte, error) {
+	body, _, err := f.getReportingCache(ctx, url)
+	return body, err
+}
+
+func (f *HTTPFetcher) getReportingCache(ctx context.Context, url string) ([]byte, bool, error) {
+	var cached *cachedResponse
+	if cacheMode != CacheDisabled {
+		if entry, ok := httpCache.load(url); ok {
+			cached = entry
+		}
+	}
+
+	if cacheMode == CacheOnly {
+		if cached == nil {
+			return nil, false, newFetchError(url, ErrorKindNotCached, 0, ErrNotCached)
+		}
+		return cached.Body, true, nil
+	}
+	if cached != nil && cached.fresh(time.Now()) && !mustRevalidate(ctx) {
+		debugf("serving cached response for URL: %s", url)
+		return cached.Body, true, nil
+	}
+
+	profile := nextHeaderProfile()
+	backoff := tuning.InitialBackoff
+	var lastErr *FetchError
+	var retryAfter time.Duration
+
+	for attempt := 0; attempt <= tuning.MaxRetries; attempt++ {
+		if attempt > 0 {
+			// A Retry-After pause is enforced by the limiter for every worker,
+			// so only back off on our own when the server gave no hint.
+			if retryAfter == 0 {
+				delay := utils.DelayTime(backoff)
+				debugf("Retry attempt %d for URL: %s after waiting %v", attempt, url, delay)
+				if err := utils.SleepContext(ctx, delay); err != nil {
+					return nil, false, newFetchError(url, ErrorKindCancelled, 0, err)
+				}
+			}
+			backoff = utils.BackoffTime(backoff, tuning.BackoffFactor)
+		}
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, false, newFetchError(url, ErrorKindCancelled, 0, err)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, false, newFetchError(url, ErrorKindNetwork, 0, fmt.Errorf("failed to create request: %w", err))
+		}
+		// Reduce anti-bot 403s by mimicking a normal browser request.
+		applyRequestHeaders(req, profile)
+		if cached != nil {
+			// Stale entry: let the server answer 304 if nothing changed.
+			if cached.ETag != "" {
+				req.Header.Set("If-None-Match", cached.ETag)
+			}
+			if cached.LastModified != "" {
+				req.Header.Set("If-Modified-Since", cached.LastModified)
+			}
+		}
+
+		resp, err := f.Client.Do(req)
+		if err != nil {
+			debugf("failed to fetch URL (attempt %d): %v", attempt, err)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, false, newFetchError(url, ErrorKindCancelled, 0, ctxErr)
+			}
+			lastErr = newFetchError(url, ErrorKindNetwork, 0, err)
+			continue
+		}
+
+		if resp.StatusCode == http.StatusNotModified && cached != nil {
+			resp.Body.Close()
+			limiter.Success()
+			debugf("cached response revalidated for URL: %s", url)
+			cached.FetchedAt = time.Now()
+			httpCache.store(cached)
+			return cached.Body, true, nil
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, false, newFetchError(url, ErrorKindNetwork, 0, fmt.Errorf("failed to read response body: %w", err))
+			}
+			limiter.Success()
+			if cacheMode != CacheDisabled {
+				httpCache.store(&cachedResponse{
+					URL:          url,
+					ETag:         resp.Header.Get("ETag"),
+					LastModified: resp.Header.Get("Last-Modified"),
+					FetchedAt:    time.Now(),
+					Body:         body,
+				})
+			}
+			return body, false, nil
+		}
+		resp.Body.Close()
+
+		kind := ErrorKindHTTPStatus
+		if isChallengeResponse(resp) {
+			kind = ErrorKindAntiBot
+		}
+		lastErr = newFetchError(url, kind, resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status))
+		if !isThrottleStatus(resp.StatusCode) {
+			return nil, false, lastErr
+		}
+		retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		limiter.Throttle(resp.StatusCode, retryAfter)
+	}
+
+	return nil, false, newFetchError(url, lastErr.Kind, lastErr.StatusCode,
+		fmt.Errorf("gave up after %d attempts: %w", tuning.MaxRetries+1, lastErr.Err))
+}
+
+// isChallengeResponse spots block pages from the CDN in front of the site,
+// which answers 403/503 with a browser challenge instead of the page.
+func isChallengeResponse(resp *http.Response) bool {
+	return strings.EqualFold(resp.Header.Get("Cf-Mitigated"), "challenge")
+}
@@ -0,0 +1,52 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// stubFetcher serves canned pages keyed by URL.
+type stubFetcher map[string]string
+
+func (s stubFetcher) Get(_ context.Context, url string) ([]byte, error) {
+	body, ok := s[url]
+	if !ok {
+		return nil, fmt.Errorf("no stub for %s", url)
+	}
+	return []byte(body), nil
+}
+
+func useFetcher(t *testing.T, f Fetcher) {
+	t.Helper()
+	previous := fetcher
+	SetFetcher(f)
+	t.Cleanup(func() {
+		fetcher = previous
+	})
+}
+
+func TestSetFetcherRoutesScrapingThroughCustomFetcher(t *testing.T) {
+	url := "https://www.examtopics.com/discussions/cisco/"
+	useTuning(t, fastTuning())
+	useFetcher(t, stubFetcher{
+		url: `<div class="discussion-list-page-indicator">Page <strong>1</strong> of <strong>7</strong></div>
+<a href="/discussions/cisco/view/1-exam-200-301-topic-1-question-1-discussion/">Question 1</a>`,
+	})
+
+	if got := getMaxNumPages(context.Background(), url); got != 7 {
+		t.Fatalf("getMaxNumPages: want 7, got %d", got)
+	}
+
+	if _, err := ParseHTML(context.Background(), url+"missing"); err == nil {
+		t.Fatal("expected ParseHTML to surface the fetcher error")
+	}
+}
+
+func TestSetFetcherNilRestoresDefault(t *testing.T) {
+	useFetcher(t, stubFetcher{})
+	SetFetcher(nil)
+	if _, ok := fetcher.(*HTTPFetcher); !ok {
+		t.Fatalf("expected default HTTPFetcher, got %T", fetcher)
+	}
+}
@@ -0,0 +1,182 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FixtureArchiveVersion is the current fixture archive format.
+const FixtureArchiveVersion = 1
+
+// FixtureArchive is every page fetched during a recorded run. Bodies are
+// stored as text so archives stay readable and diffable in testdata/.
+type FixtureArchive struct {
+	Version    int            `json:"version"`
+	RecordedAt time.Time      `json:"recorded_at"`
+	Entries    []FixtureEntry `json:"entries"`
+}
+
+type FixtureEntry struct {
+	URL  string `json:"url"`
+	Body string `json:"body"`
+}
+
+func LoadFixtureArchive(path string) (*FixtureArchive, error) {
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture archive %q: %w", path, err)
+	}
+
+	var archive FixtureArchive
+	if err := json.Unmarshal(payload, &archive); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture archive %q: %w", path, err)
+	}
+	if archive.Version > FixtureArchiveVersion {
+		return nil, fmt.Errorf("fixture archive %q has version %d; this build supports up to %d", path, archive.Version, FixtureArchiveVersion)
+	}
+	return &archive, nil
+}
+
+// RecordingFetcher passes requests through to another Fetcher and keeps
+// every successful response so it can be saved as a FixtureArchive.
+type RecordingFetcher struct {
+	next Fetcher
+
+	mu      sync.Mutex
+	entries map[string]string
+}
+
+func NewRecordingFetcher(next Fetcher) *RecordingFetcher {
+	return &RecordingFetcher{next: next, entries: map[string]string{}}
+}
+
+func (r *RecordingFetcher) Get(ctx context.Context, url string) ([]byte, error) {
+	body, _, err := r.getReportingCache(ctx, url)
+	return body, err
+}
+
+func (r *RecordingFetcher) getReportingCache(ctx context.Context, url string) ([]byte, bool, error) {
+	body, fromCache, err := fetchPage(ctx, r.next, url)
+	if err != nil {
+		return body, fromCache, err
+	}
+
+	r.mu.Lock()
+	r.entries[url] = string(body)
+	r.mu.Unlock()
+	return body, fromCache, nil
+}
+
+// Len reports how many distinct URLs have been recorded.
+func (r *RecordingFetcher) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.entries)
+}
+
+// Save writes the recorded responses to path, sorted by URL.
+func (r *RecordingFetcher) Save(path string) error {
+	r.mu.Lock()
+	archive := FixtureArchive{
+		Version:    FixtureArchiveVersion,
+		RecordedAt: time.Now().UTC(),
+		Entries:    make([]FixtureEntry, 0, len(r.entries)),
+	}
+	for u, body := range r.entries {
+		archive.Entries = append(archive.Entries, FixtureEntry{URL: u, Body: body})
+	}
+	r.mu.Unlock()
+
+	sort.Slice(archive.Entries, func(i, j int) bool {
+		return archive.Entries[i].URL < archive.Entries[j].URL
+	})
+
+	payload, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode fixture archive: %w", err)
+	}
+	if err := os.WriteFile(path, payload, 0o644); err != nil {
+		return fmt.Errorf("failed to write fixture archive %q: %w", path, err)
+	}
+	return nil
+}
+
+// ReplayServer serves a FixtureArchive from a local httptest server. Pages
+// are matched on path and query only, so the recorded host does not matter;
+// anything missing from the archive answers 404.
+type ReplayServer struct {
+	*httptest.Server
+
+	pages map[string]string
+
+	mu     sync.Mutex
+	misses []string
+}
+
+func NewReplayServer(archive *FixtureArchive) *ReplayServer {
+	s := &ReplayServer{pages: make(map[string]string, len(archive.Entries))}
+	for _, entry := range archive.Entries {
+		if key := replayKey(entry.URL); key != "" {
+			s.pages[key] = entry.Body
+		}
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := s.pages[r.URL.RequestURI()]
+		if !ok {
+			s.mu.Lock()
+			s.misses = append(s.misses, r.URL.RequestURI())
+			s.mu.Unlock()
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, body)
+	}))
+	return s
+}
+
+// Fetcher returns a Fetcher that sends every request to the replay server,
+// whatever host the URL names.
+func (s *ReplayServer) Fetcher() Fetcher {
+	target, _ := url.Parse(s.URL)
+	client := s.Client()
+	client.Transport = &rewriteHostTransport{target: target, next: client.Transport}
+	return NewHTTPFetcher(client)
+}
+
+// Misses lists the request URIs that were not in the archive.
+func (s *ReplayServer) Misses() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.misses...)
+}
+
+func replayKey(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.RequestURI()
+}
+
+type rewriteHostTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (t *rewriteHostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rewritten := req.Clone(req.Context())
+	rewritten.URL.Scheme = t.target.Scheme
+	rewritten.URL.Host = t.target.Host
+	rewritten.Host = t.target.Host
+	return t.next.RoundTrip(rewritten)
+}
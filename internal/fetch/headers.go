@@ -0,0 +1,188 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"examtopics-downloader/internal/utils"
+)
+
+// HeaderProfile is a named set of request headers that imitates one browser.
+type HeaderProfile struct {
+	Name    string
+	Headers map[string]string
+}
+
+// AllHeaderProfiles selects every built-in and custom profile for rotation.
+const AllHeaderProfiles = "all"
+
+// DefaultHeaderProfile is used when no profile is selected.
+const DefaultHeaderProfile = "chrome"
+
+const (
+	acceptHTML     = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+	acceptHTMLAVIF = "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"
+)
+
+var builtinHeaderProfiles = []HeaderProfile{
+	{Name: "chrome", Headers: map[string]string{
+		"User-Agent":         "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/132.0.0.0 Safari/537.36",
+		"Accept":             acceptHTML,
+		"Accept-Language":    "en-US,en;q=0.9",
+		"Sec-Ch-Ua":          `"Not A(Brand";v="8", "Chromium";v="132", "Google Chrome";v="132"`,
+		"Sec-Ch-Ua-Mobile":   "?0",
+		"Sec-Ch-Ua-Platform": `"Windows"`,
+	}},
+	{Name: "edge", Headers: map[string]string{
+		"User-Agent":         "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/132.0.0.0 Safari/537.36 Edg/132.0.0.0",
+		"Accept":             acceptHTML,
+		"Accept-Language":    "en-US,en;q=0.9",
+		"Sec-Ch-Ua":          `"Not A(Brand";v="8", "Chromium";v="132", "Microsoft Edge";v="132"`,
+		"Sec-Ch-Ua-Mobile":   "?0",
+		"Sec-Ch-Ua-Platform": `"Windows"`,
+	}},
+	{Name: "firefox", Headers: map[string]string{
+		"User-Agent":      "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:134.0) Gecko/20100101 Firefox/134.0",
+		"Accept":          acceptHTMLAVIF,
+		"Accept-Language": "en-US,en;q=0.5",
+	}},
+	{Name: "safari", Headers: map[string]string{
+		"User-Agent":      "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.2 Safari/605.1.15",
+		"Accept":          acceptHTML,
+		"Accept-Language": "en-US,en;q=0.9",
+	}},
+}
+
+// reservedHeaders are set by the fetcher or the transport and cannot be
+// overridden by a profile. Setting Accept-Encoding by hand would also turn
+// off transparent gzip decoding.
+var reservedHeaders = []string{"Accept-Encoding", "Cookie", "Host", "If-Modified-Since", "If-None-Match", "Referer"}
+
+var (
+	headerProfiles = []HeaderProfile{builtinHeaderProfiles[0]}
+	headerRotation atomic.Uint64
+)
+
+// HeaderProfileNames lists the built-in profiles.
+func HeaderProfileNames() []string {
+	names := make([]string, 0, len(builtinHeaderProfiles))
+	for _, profile := range builtinHeaderProfiles {
+		names = append(names, profile.Name)
+	}
+	return names
+}
+
+// SetHeaderProfiles selects the profiles sent with requests. custom adds
+// profiles by name (a custom profile may replace a built-in one) and must set
+// User-Agent. selection names the profiles to use, or AllHeaderProfiles;
+// several profiles are rotated per request. An empty selection uses
+// DefaultHeaderProfile.
+func SetHeaderProfiles(custom map[string]map[string]string, selection []string) error {
+	available := map[string]HeaderProfile{}
+	var order []string
+	for _, profile := range builtinHeaderProfiles {
+		available[profile.Name] = profile
+		order = append(order, profile.Name)
+	}
+
+	customNames := make([]string, 0, len(custom))
+	for name := range custom {
+		customNames = append(customNames, name)
+	}
+	sort.Strings(customNames)
+	for _, rawName := range customNames {
+		name := strings.ToLower(strings.TrimSpace(rawName))
+		headers := map[string]string{}
+		for key, value := range custom[rawName] {
+			headers[http.CanonicalHeaderKey(strings.TrimSpace(key))] = value
+		}
+		if err := validateHeaderProfile(name, headers); err != nil {
+			return err
+		}
+		if _, exists := available[name]; !exists {
+			order = append(order, name)
+		}
+		available[name] = HeaderProfile{Name: name, Headers: headers}
+	}
+
+	var selected []HeaderProfile
+	for _, rawName := range selection {
+		name := strings.ToLower(strings.TrimSpace(rawName))
+		switch {
+		case name == "":
+			continue
+		case name == AllHeaderProfiles:
+			for _, n := range order {
+				selected = append(selected, available[n])
+			}
+		default:
+			profile, ok := available[name]
+			if !ok {
+				return fmt.Errorf("unknown header profile %q (available: %s)", name, strings.Join(order, ", "))
+			}
+			selected = append(selected, profile)
+		}
+	}
+	if len(selected) == 0 {
+		selected = []HeaderProfile{available[DefaultHeaderProfile]}
+	}
+
+	headerProfiles = selected
+	headerRotation.Store(0)
+	return nil
+}
+
+func validateHeaderProfile(name string, headers map[string]string) error {
+	if name == "" || name == AllHeaderProfiles {
+		return fmt.Errorf("header profile name %q is not allowed", name)
+	}
+	if strings.TrimSpace(headers["User-Agent"]) == "" {
+		return fmt.Errorf("header profile %q must set User-Agent", name)
+	}
+	for _, reserved := range reservedHeaders {
+		if _, ok := headers[reserved]; ok {
+			return fmt.Errorf("header profile %q cannot set %s", name, reserved)
+		}
+	}
+	return nil
+}
+
+// nextHeaderProfile returns the profile for the next request, cycling
+// through the selection.
+func nextHeaderProfile() HeaderProfile {
+	profiles := headerProfiles
+	if len(profiles) == 1 {
+		return profiles[0]
+	}
+	return profiles[(headerRotation.Add(1)-1)%uint64(len(profiles))]
+}
+
+type refererKey struct{}
+
+// withReferer makes requests made with ctx send url as their Referer, e.g.
+// the discussion list page a question was linked from.
+func withReferer(ctx context.Context, url string) context.Context {
+	if url == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, refererKey{}, url)
+}
+
+// refererFor returns the page set with withReferer, or the site root.
+func refererFor(ctx context.Context) string {
+	if url, ok := ctx.Value(refererKey{}).(string); ok {
+		return url
+	}
+	return utils.BaseURL() + "/"
+}
+
+func applyRequestHeaders(req *http.Request, profile HeaderProfile) {
+	for key, value := range profile.Headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Referer", refererFor(req.Context()))
+}
@@ -0,0 +1,95 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func useHeaderProfiles(t *testing.T, custom map[string]map[string]string, selection ...string) {
+	t.Helper()
+	previous := headerProfiles
+	if err := SetHeaderProfiles(custom, selection); err != nil {
+		t.Fatalf("SetHeaderProfiles: %v", err)
+	}
+	t.Cleanup(func() { headerProfiles = previous })
+}
+
+func TestSetHeaderProfilesRotatesSelection(t *testing.T) {
+	useHeaderProfiles(t, map[string]map[string]string{
+		"Bot": {"user-agent": "examtopics-downloader/1.0"},
+	}, "firefox", "bot")
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, nextHeaderProfile().Name)
+	}
+	if strings.Join(got, ",") != "firefox,bot,firefox,bot" {
+		t.Fatalf("rotation order: got %q", got)
+	}
+	if ua := headerProfiles[1].Headers["User-Agent"]; ua != "examtopics-downloader/1.0" {
+		t.Fatalf("custom header keys should be canonicalized, got %q", headerProfiles[1].Headers)
+	}
+
+	useHeaderProfiles(t, nil, AllHeaderProfiles)
+	if len(headerProfiles) != len(builtinHeaderProfiles) {
+		t.Fatalf("%q: want %d profiles, got %d", AllHeaderProfiles, len(builtinHeaderProfiles), len(headerProfiles))
+	}
+	useHeaderProfiles(t, nil)
+	if nextHeaderProfile().Name != DefaultHeaderProfile {
+		t.Fatalf("empty selection should use %q", DefaultHeaderProfile)
+	}
+}
+
+func TestSetHeaderProfilesRejectsInvalidProfiles(t *testing.T) {
+	previous := headerProfiles
+	t.Cleanup(func() { headerProfiles = previous })
+
+	tests := []struct {
+		custom    map[string]map[string]string
+		selection []string
+	}{
+		{selection: []string{"netscape"}},
+		{custom: map[string]map[string]string{"bare": {"Accept": "*/*"}}},
+		{custom: map[string]map[string]string{"gzip": {"User-Agent": "x", "accept-encoding": "gzip"}}},
+		{custom: map[string]map[string]string{"all": {"User-Agent": "x"}}},
+	}
+	for _, tt := range tests {
+		if err := SetHeaderProfiles(tt.custom, tt.selection); err == nil {
+			t.Fatalf("SetHeaderProfiles(%v, %v): expected an error", tt.custom, tt.selection)
+		}
+	}
+}
+
+func TestHTTPFetcherSendsProfileAndParentReferer(t *testing.T) {
+	useTempHTTPCache(t, CacheDisabled)
+	useTuning(t, fastTuning())
+	useHeaderProfiles(t, nil, "safari")
+
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	listPage := "https://www.examtopics.com/discussions/cisco/3"
+	if _, err := NewHTTPFetcher(server.Client()).Get(withReferer(context.Background(), listPage), server.URL); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got := header.Get("Referer"); got != listPage {
+		t.Fatalf("Referer: want %q, got %q", listPage, got)
+	}
+	if !strings.Contains(header.Get("User-Agent"), "Version/18.2 Safari") {
+		t.Fatalf("User-Agent should come from the safari profile, got %q", header.Get("User-Agent"))
+	}
+
+	if _, err := NewHTTPFetcher(server.Client()).Get(context.Background(), server.URL); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got := header.Get("Referer"); got != "https://www.examtopics.com/" {
+		t.Fatalf("Referer without a parent page: want the site root, got %q", got)
+	}
+}
@@ -0,0 +1,233 @@
+package fetch
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheMode controls how HTTPFetcher uses the on-disk response cache.
+type CacheMode int
+
+const (
+	// CacheDefault serves fresh entries from disk and revalidates stale ones
+	// with conditional requests.
+	CacheDefault CacheMode = iota
+	// CacheDisabled always goes to the network and stores nothing.
+	CacheDisabled
+	// CacheOnly never touches the network; stale entries are served as-is
+	// and misses fail.
+	CacheOnly
+)
+
+// Freshness lifetimes per URL class. Provider and exam indexes change
+// rarely, discussion lists gain new entries daily, and a question page only
+// changes when comments are added.
+const (
+	providerIndexCacheTTL  = 24 * time.Hour
+	discussionListCacheTTL = time.Hour
+	questionPageCacheTTL   = 7 * 24 * time.Hour
+	defaultCacheTTL        = time.Hour
+)
+
+var (
+	questionPagePathPattern   = regexp.MustCompile(`(?i)^/discussions/[a-z0-9-]+/view/`)
+	discussionListPathPattern = regexp.MustCompile(`(?i)^/discussions/[a-z0-9-]+/(?:\d+/?)?$`)
+	providerIndexPathPattern  = regexp.MustCompile(`(?i)^/(?:exams|discussions)/(?:[a-z0-9-]+/?)?$`)
+)
+
+var (
+	cacheMode = CacheDefault
+	httpCache = newResponseCache(defaultHTTPCacheDir())
+)
+
+func SetCacheMode(mode CacheMode) {
+	cacheMode = mode
+}
+
+type revalidateKey struct{}
+
+// withRevalidation makes requests made with ctx revalidate cached entries
+// even while fresh, e.g. to refetch a question whose comments changed.
+func withRevalidation(ctx context.Context) context.Context {
+	return context.WithValue(ctx, revalidateKey{}, true)
+}
+
+func mustRevalidate(ctx context.Context) bool {
+	revalidate, _ := ctx.Value(revalidateKey{}).(bool)
+	return revalidate
+}
+
+type cachedResponse struct {
+	URL          string    `json:"url"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+	// Session is set for pages fetched with imported session cookies, which
+	// are cached apart from guest pages.
+	Session bool   `json:"session,omitempty"`
+	Body    []byte `json:"body"`
+}
+
+func (c *cachedResponse) fresh(now time.Time) bool {
+	return now.Sub(c.FetchedAt) < cacheTTLForURL(c.URL)
+}
+
+// responseCache stores one JSON file per URL, named by the URL's hash.
+type responseCache struct {
+	mu  sync.Mutex
+	dir string
+}
+
+func newResponseCache(dir string) *responseCache {
+	return &responseCache{dir: dir}
+}
+
+func (c *responseCache) load(rawURL string) (*cachedResponse, bool) {
+	if c == nil || c.dir == "" {
+		return nil, false
+	}
+
+	payload, err := os.ReadFile(c.pathFor(rawURL))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry cachedResponse
+	if err := json.Unmarshal(payload, &entry); err != nil {
+		debugf("ignoring unreadable cache entry for %s: %v", rawURL, err)
+		return nil, false
+	}
+	if entry.URL != rawURL || entry.Session != sessionCached() {
+		return nil, false
+	}
+	return &entry, true
+}
+
+func (c *responseCache) store(entry *cachedResponse) {
+	if c == nil || c.dir == "" {
+		return
+	}
+
+	entry.Session = sessionCached()
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		debugf("failed to encode cache entry for %s: %v", entry.URL, err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	target := c.pathFor(entry.URL)
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		debugf("failed to create cache dir %q: %v", dir, err)
+		return
+	}
+
+	// Write then rename so concurrent readers never see a partial file.
+	tmp, err := os.CreateTemp(dir, "entry-*.tmp")
+	if err != nil {
+		debugf("failed to create cache entry for %s: %v", entry.URL, err)
+		return
+	}
+	_, writeErr := tmp.Write(payload)
+	closeErr := tmp.Close()
+	if writeErr != nil || closeErr != nil {
+		os.Remove(tmp.Name())
+		debugf("failed to write cache entry for %s: %v %v", entry.URL, writeErr, closeErr)
+		return
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		os.Remove(tmp.Name())
+		debugf("failed to save cache entry for %s: %v", entry.URL, err)
+	}
+}
+
+// remove drops the entry for rawURL, e.g. once its body turned out to be a
+// challenge page.
+func (c *responseCache) remove(rawURL string) {
+	if c == nil || c.dir == "" {
+		return
+	}
+	if err := os.Remove(c.pathFor(rawURL)); err != nil && !os.IsNotExist(err) {
+		debugf("failed to remove cache entry for %s: %v", rawURL, err)
+	}
+}
+
+// removeSessionEntries drops every page cached with a session, e.g. once
+// fresh cookies are imported and pages cached while the old session had
+// expired would otherwise be served as a guest.
+func (c *responseCache) removeSessionEntries() {
+	if c == nil || c.dir == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.RemoveAll(c.sessionDir()); err != nil {
+		debugf("failed to remove session cache entries: %v", err)
+	}
+}
+
+// pathFor keys entries by URL. Pages fetched with a session live in their
+// own directory, so adding or dropping --cookies never serves pages cached
+// the other way.
+func (c *responseCache) pathFor(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	dir := c.dir
+	if sessionCached() {
+		dir = c.sessionDir()
+	}
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *responseCache) sessionDir() string {
+	return filepath.Join(c.dir, "session")
+}
+
+// sessionCached reports whether responses are fetched, and cached, with
+// the imported session cookies.
+func sessionCached() bool {
+	return sessionJar != nil
+}
+
+func cacheTTLForURL(rawURL string) time.Duration {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return defaultCacheTTL
+	}
+
+	path := strings.ToLower(parsed.EscapedPath())
+	if path == "" {
+		path = "/"
+	}
+
+	switch {
+	case questionPagePathPattern.MatchString(path):
+		return questionPageCacheTTL
+	case discussionListPathPattern.MatchString(path):
+		return discussionListCacheTTL
+	case providerIndexPathPattern.MatchString(path):
+		return providerIndexCacheTTL
+	default:
+		return defaultCacheTTL
+	}
+}
+
+func defaultHTTPCacheDir() string {
+	baseDir, err := os.UserCacheDir()
+	if err == nil && strings.TrimSpace(baseDir) != "" {
+		return filepath.Join(baseDir, "examtopics-downloader", "http")
+	}
+	return filepath.Join(".", ".examtopics_http_cache")
+}
@@ -0,0 +1,141 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// useTempHTTPCache points the response cache and the link index at a
+// temporary directory for the test.
+func useTempHTTPCache(t *testing.T, mode CacheMode) {
+	t.Helper()
+	previousCache, previousIndexes, previousMode := httpCache, linkIndexes, cacheMode
+	dir := t.TempDir()
+	httpCache = newResponseCache(filepath.Join(dir, "http"))
+	linkIndexes = newLinkIndexStore(filepath.Join(dir, "link_index"))
+	cacheMode = mode
+	t.Cleanup(func() {
+		httpCache, linkIndexes, cacheMode = previousCache, previousIndexes, previousMode
+	})
+}
+
+func TestCacheTTLForURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want time.Duration
+	}{
+		{url: "https://www.examtopics.com/exams/", want: providerIndexCacheTTL},
+		{url: "https://www.examtopics.com/exams/cisco/", want: providerIndexCacheTTL},
+		{url: "https://www.examtopics.com/discussions/", want: providerIndexCacheTTL},
+		{url: "https://www.examtopics.com/discussions/cisco/", want: discussionListCacheTTL},
+		{url: "https://www.examtopics.com/discussions/cisco/42", want: discussionListCacheTTL},
+		{url: "https://www.examtopics.com/discussions/cisco/view/1-exam-200-301-topic-1-question-1-discussion/", want: questionPageCacheTTL},
+	}
+
+	for _, tc := range tests {
+		if got := cacheTTLForURL(tc.url); got != tc.want {
+			t.Fatalf("cacheTTLForURL(%q): want %v, got %v", tc.url, tc.want, got)
+		}
+	}
+}
+
+func TestHTTPFetcherRevalidatesStaleEntryWithETag(t *testing.T) {
+	useTempHTTPCache(t, CacheDefault)
+
+	var requests, conditional atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			conditional.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("<html>page</html>"))
+	}))
+	defer server.Close()
+
+	f := NewHTTPFetcher(server.Client())
+	url := server.URL + "/discussions/cisco/3"
+	if body, err := f.Get(context.Background(), url); err != nil || string(body) != "<html>page</html>" {
+		t.Fatalf("unexpected first body: %q (err %v)", body, err)
+	}
+
+	// A fresh entry is served without touching the network.
+	if body, err := f.Get(context.Background(), url); err != nil || string(body) != "<html>page</html>" {
+		t.Fatalf("unexpected cached body: %q (err %v)", body, err)
+	}
+	if requests.Load() != 1 {
+		t.Fatalf("expected 1 request while fresh, got %d", requests.Load())
+	}
+
+	entry, ok := httpCache.load(url)
+	if !ok {
+		t.Fatal("expected response to be cached")
+	}
+	entry.FetchedAt = time.Now().Add(-2 * discussionListCacheTTL)
+	httpCache.store(entry)
+
+	if body, err := f.Get(context.Background(), url); err != nil || string(body) != "<html>page</html>" {
+		t.Fatalf("unexpected revalidated body: %q (err %v)", body, err)
+	}
+	if conditional.Load() != 1 {
+		t.Fatalf("expected a conditional request for the stale entry, got %d", conditional.Load())
+	}
+}
+
+func TestHTTPFetcherCacheOnlyNeverUsesNetwork(t *testing.T) {
+	useTempHTTPCache(t, CacheOnly)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected network request for %s", r.URL)
+	}))
+	defer server.Close()
+
+	f := NewHTTPFetcher(server.Client())
+	url := server.URL + "/exams/"
+	if _, err := f.Get(context.Background(), url); !errors.Is(err, ErrNotCached) {
+		t.Fatalf("expected cache miss to return ErrNotCached, got %v", err)
+	}
+
+	httpCache.store(&cachedResponse{URL: url, FetchedAt: time.Now().Add(-365 * 24 * time.Hour), Body: []byte("old")})
+	if body, err := f.Get(context.Background(), url); err != nil || string(body) != "old" {
+		t.Fatalf("expected stale cached body in cache-only mode, got %q (err %v)", body, err)
+	}
+}
+
+func TestHTTPFetcherRevalidatesFreshEntryOnRequest(t *testing.T) {
+	useTempHTTPCache(t, CacheDefault)
+
+	var requests, conditional atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		if r.Header.Get("If-None-Match") != "" {
+			conditional.Add(1)
+		}
+		w.Header().Set("ETag", `"v`+strconv.Itoa(int(n))+`"`)
+		w.Write([]byte("<html>page " + strconv.Itoa(int(n)) + "</html>"))
+	}))
+	defer server.Close()
+
+	f := NewHTTPFetcher(server.Client())
+	url := server.URL + "/discussions/cisco/view/1-exam-200-301-topic-1-question-1-discussion/"
+	if _, err := f.Get(context.Background(), url); err != nil {
+		t.Fatalf("first fetch: %v", err)
+	}
+
+	body, err := f.Get(withRevalidation(context.Background()), url)
+	if err != nil || string(body) != "<html>page 2</html>" {
+		t.Fatalf("want the updated page, got %q (err %v)", body, err)
+	}
+	if conditional.Load() != 1 {
+		t.Fatalf("expected a conditional request, got %d", conditional.Load())
+	}
+}
@@ -0,0 +1,258 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The limiter halves its rate on every throttling response and adds back a
+// tenth of the configured rate after each run of successes, never dropping
+// below a sixteenth of it.
+const (
+	limiterMinRateDivisor    = 16
+	limiterRampStep          = 0.1
+	limiterSuccessesPerRamp  = 10
+	limiterMaxRetryAfterWait = 5 * time.Minute
+)
+
+// ThrottleStats summarizes how often the site pushed back.
+type ThrottleStats struct {
+	Events          int         `json:"events"`
+	ByStatus        map[int]int `json:"by_status,omitempty"`
+	RetryAfterWaits int         `json:"retry_after_waits"`
+	BlockedPages    int         `json:"blocked_pages,omitempty"`
+	LowestRate      float64     `json:"lowest_rate"`
+}
+
+// adaptiveLimiter is a process-wide token bucket shared by every request
+// HTTPFetcher sends. Throttling responses shrink the rate and Retry-After
+// pauses the bucket; successes slowly ramp it back to the configured rate.
+type adaptiveLimiter struct {
+	mu          sync.Mutex
+	maxRate     float64
+	rate        float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+	successes   int
+	stats       ThrottleStats
+}
+
+func newAdaptiveLimiter(rate float64) *adaptiveLimiter {
+	l := &adaptiveLimiter{}
+	l.reset(rate)
+	return l
+}
+
+func (l *adaptiveLimiter) reset(rate float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.maxRate = rate
+	l.rate = rate
+	l.tokens = 1
+	l.last = time.Now()
+	l.pausedUntil = time.Time{}
+	l.successes = 0
+	l.stats = ThrottleStats{LowestRate: rate}
+}
+
+// Wait blocks until a request may be sent or ctx is done.
+func (l *adaptiveLimiter) Wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		var delay time.Duration
+		if now.Before(l.pausedUntil) {
+			delay = l.pausedUntil.Sub(now)
+		} else {
+			l.tokens += now.Sub(l.last).Seconds() * l.rate
+			if l.tokens > 1 {
+				l.tokens = 1
+			}
+			l.last = now
+			if l.tokens >= 1 {
+				l.tokens--
+				l.mu.Unlock()
+				return nil
+			}
+			delay = time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		}
+		l.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// Throttle records a throttling response and slows down.
+func (l *adaptiveLimiter) Throttle(status int, retryAfter time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.successes = 0
+	l.slowDownLocked()
+
+	if retryAfter > 0 {
+		if retryAfter > limiterMaxRetryAfterWait {
+			retryAfter = limiterMaxRetryAfterWait
+		}
+		if until := time.Now().Add(retryAfter); until.After(l.pausedUntil) {
+			l.pausedUntil = until
+		}
+		l.stats.RetryAfterWaits++
+	}
+
+	l.stats.Events++
+	if l.stats.ByStatus == nil {
+		l.stats.ByStatus = map[int]int{}
+	}
+	l.stats.ByStatus[status]++
+	debugf("throttled with status %d; rate now %.2f req/s", status, l.rate)
+}
+
+// Blocked records a challenge, login wall or empty page served with status
+// 200. It slows down like a throttling response but is counted apart.
+func (l *adaptiveLimiter) Blocked() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.successes = 0
+	l.slowDownLocked()
+	l.stats.BlockedPages++
+	debugf("blocked page served; rate now %.2f req/s", l.rate)
+}
+
+func (l *adaptiveLimiter) slowDownLocked() {
+	minRate := l.maxRate / limiterMinRateDivisor
+	l.rate /= 2
+	if l.rate < minRate {
+		l.rate = minRate
+	}
+	if l.rate < l.stats.LowestRate {
+		l.stats.LowestRate = l.rate
+	}
+}
+
+// Success records a completed request and ramps the rate back up.
+func (l *adaptiveLimiter) Success() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate >= l.maxRate {
+		return
+	}
+	l.successes++
+	if l.successes < limiterSuccessesPerRamp {
+		return
+	}
+	l.successes = 0
+	l.rate += l.maxRate * limiterRampStep
+	if l.rate > l.maxRate {
+		l.rate = l.maxRate
+	}
+}
+
+// Rate reports the current requests per second.
+func (l *adaptiveLimiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+func (l *adaptiveLimiter) Stats() ThrottleStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats := l.stats
+	stats.ByStatus = make(map[int]int, len(l.stats.ByStatus))
+	for status, count := range l.stats.ByStatus {
+		stats.ByStatus[status] = count
+	}
+	return stats
+}
+
+func (l *adaptiveLimiter) resetStats() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stats = ThrottleStats{LowestRate: l.rate}
+}
+
+var limiter = newAdaptiveLimiter(tuning.RequestsPerSecond)
+
+// ResetStats clears the throttling counters and the expired-session flag.
+func ResetStats() {
+	limiter.resetStats()
+	sessionExpired.Store(false)
+}
+
+// Throttle reports the throttling seen since the last ResetStats.
+func Throttle() ThrottleStats {
+	return limiter.Stats()
+}
+
+// formatThrottleSummary describes throttling for the end-of-run summary, or
+// returns "" when the site never pushed back.
+func formatThrottleSummary(stats ThrottleStats) string {
+	if stats.Events == 0 && stats.BlockedPages == 0 {
+		return ""
+	}
+	if stats.Events == 0 {
+		return fmt.Sprintf("Received %d challenge, login or empty page(s); slowed down to %.2f req/s.",
+			stats.BlockedPages, stats.LowestRate)
+	}
+
+	statuses := make([]int, 0, len(stats.ByStatus))
+	for status := range stats.ByStatus {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	parts := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		parts = append(parts, fmt.Sprintf("%d x%d", status, stats.ByStatus[status]))
+	}
+
+	summary := fmt.Sprintf("Throttled %d time(s) (%s); slowed down to %.2f req/s.",
+		stats.Events, strings.Join(parts, ", "), stats.LowestRate)
+	if stats.RetryAfterWaits > 0 {
+		summary += fmt.Sprintf(" Honored Retry-After %d time(s).", stats.RetryAfterWaits)
+	}
+	if stats.BlockedPages > 0 {
+		summary += fmt.Sprintf(" Received %d challenge, login or empty page(s).", stats.BlockedPages)
+	}
+	return summary
+}
+
+func isThrottleStatus(status int) bool {
+	return status == http.StatusTooManyRequests ||
+		status == http.StatusForbidden ||
+		status == http.StatusServiceUnavailable
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP
+// date. It returns 0 when the header is missing or unusable.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds <= 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(value); err == nil && when.After(now) {
+		return when.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,100 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func useTuning(t *testing.T, tn Tuning) {
+	t.Helper()
+	previous := tuning
+	SetTuning(tn)
+	t.Cleanup(func() {
+		SetTuning(previous)
+	})
+}
+
+// fastTuning keeps tests quick while still exercising the limiter.
+func fastTuning() Tuning {
+	return Tuning{
+		RequestsPerSecond:     50,
+		MaxConcurrentRequests: 4,
+		MaxRetries:            3,
+		InitialBackoff:        time.Millisecond,
+		BackoffFactor:         2,
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: 0},
+		{value: "7", want: 7 * time.Second},
+		{value: "-3", want: 0},
+		{value: now.Add(90 * time.Second).Format(http.TimeFormat), want: 90 * time.Second},
+		{value: "soon", want: 0},
+	}
+
+	for _, tc := range tests {
+		if got := parseRetryAfter(tc.value, now); got != tc.want {
+			t.Fatalf("parseRetryAfter(%q): want %v, got %v", tc.value, tc.want, got)
+		}
+	}
+}
+
+func TestAdaptiveLimiterBacksOffAndRampsUp(t *testing.T) {
+	l := newAdaptiveLimiter(8)
+
+	l.Throttle(http.StatusTooManyRequests, 0)
+	if got := l.Rate(); got != 4 {
+		t.Fatalf("rate after one throttle: want 4, got %g", got)
+	}
+	for i := 0; i < 10; i++ {
+		l.Throttle(http.StatusForbidden, 0)
+	}
+	if got := l.Rate(); got != 0.5 {
+		t.Fatalf("rate should bottom out at a sixteenth: want 0.5, got %g", got)
+	}
+
+	for i := 0; i < limiterSuccessesPerRamp; i++ {
+		l.Success()
+	}
+	if got := l.Rate(); got != 1.3 {
+		t.Fatalf("rate after one ramp step: want 1.3, got %g", got)
+	}
+
+	stats := l.Stats()
+	if stats.Events != 11 || stats.ByStatus[http.StatusForbidden] != 10 || stats.LowestRate != 0.5 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestHTTPFetcherRetriesThrottledResponses(t *testing.T) {
+	useTempHTTPCache(t, CacheDisabled)
+	useTuning(t, fastTuning())
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	body, err := NewHTTPFetcher(server.Client()).Get(context.Background(), server.URL+"/exams/")
+	if err != nil || string(body) != "ok" {
+		t.Fatalf("expected the 429 to be retried, got %q (err %v)", body, err)
+	}
+	if stats := limiter.Stats(); stats.ByStatus[http.StatusTooManyRequests] != 1 {
+		t.Fatalf("expected the 429 to be recorded, got %+v", stats)
+	}
+}
@@ -0,0 +1,317 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"examtopics-downloader/internal/models"
+	"examtopics-downloader/internal/utils"
+)
+
+// A provider's link index younger than linkIndexFreshFor is used without any
+// request. An older one is refreshed from page 1 until known links appear,
+// and after linkIndexRescanAfter every list page is scanned again so that
+// removed discussions drop out.
+const (
+	linkIndexFreshFor    = discussionListCacheTTL
+	linkIndexRescanAfter = 7 * 24 * time.Hour
+)
+
+// indexedLink is one discussion as listed on a provider's discussion pages.
+type indexedLink struct {
+	Link    string `json:"link"`
+	Replies int    `json:"replies"`
+	Page    int    `json:"page"`
+}
+
+// providerLinkIndex holds every discussion link of one provider in list
+// order, so discovery and every exam of the provider share one scan.
+type providerLinkIndex struct {
+	Origin    string        `json:"origin"`
+	Provider  string        `json:"provider"`
+	Links     []indexedLink `json:"links"`
+	ScannedAt time.Time     `json:"scanned_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+func (idx *providerLinkIndex) entries() []discussionLink {
+	out := make([]discussionLink, 0, len(idx.Links))
+	for _, link := range idx.Links {
+		out = append(out, discussionLink{
+			Link:     link.Link,
+			Replies:  link.Replies,
+			ListPage: discussionListPageURL(idx.Provider, link.Page),
+		})
+	}
+	return out
+}
+
+// scanProgress reports list page requests, e.g. to a progress bar. Both
+// callbacks are optional.
+type scanProgress struct {
+	addPages func(n int)
+	pageDone func()
+}
+
+func (p scanProgress) add(n int) {
+	if p.addPages != nil {
+		p.addPages(n)
+	}
+}
+
+func (p scanProgress) done() {
+	if p.pageDone != nil {
+		p.pageDone()
+	}
+}
+
+// linkIndexStore keeps provider link indexes in memory for the process and
+// on disk according to the cache mode: CacheDisabled neither reads nor
+// writes the disk, CacheOnly uses a stored index whatever its age.
+type linkIndexStore struct {
+	dir string
+
+	mu    sync.Mutex
+	slots map[string]*linkIndexSlot
+}
+
+// linkIndexSlot serializes scans of one provider, so concurrent extractions
+// wait for a single scan instead of starting their own.
+type linkIndexSlot struct {
+	mu       sync.Mutex
+	index    *providerLinkIndex
+	failures []models.FetchFailure
+}
+
+var linkIndexes = newLinkIndexStore(defaultLinkIndexDir())
+
+func newLinkIndexStore(dir string) *linkIndexStore {
+	return &linkIndexStore{dir: dir, slots: map[string]*linkIndexSlot{}}
+}
+
+func defaultLinkIndexDir() string {
+	baseDir, err := os.UserCacheDir()
+	if err == nil && strings.TrimSpace(baseDir) != "" {
+		return filepath.Join(baseDir, "examtopics-downloader", "link_index")
+	}
+	return filepath.Join(".", ".examtopics_link_index")
+}
+
+func discussionListPageURL(providerName string, page int) string {
+	return utils.AddToBaseUrl(fmt.Sprintf("/discussions/%s/%d", providerName, page))
+}
+
+// examLinks returns the discussion links of the provider that match
+// selectedExam, with the list pages that could not be fetched. fullScan is
+// passed on to providerLinks.
+func (s *linkIndexStore) examLinks(ctx context.Context, providerName, selectedExam string, fullScan bool, progress scanProgress) ([]discussionLink, []models.FetchFailure) {
+	entries, failures := s.providerLinks(ctx, providerName, fullScan, progress)
+
+	var matching []discussionLink
+	for _, entry := range entries {
+		if matchesExamSelection(providerName, selectedExam, entry.Link) {
+			matching = append(matching, entry)
+		}
+	}
+	return matching, failures
+}
+
+// providerLinks returns every discussion link of the provider, from the
+// index when it is fresh and otherwise after refreshing or rebuilding it.
+// fullScan asks for reply counts read from every list page: a refresh stops
+// at the first unchanged page, so it rescans unless the index was fully
+// scanned within linkIndexFreshFor.
+func (s *linkIndexStore) providerLinks(ctx context.Context, providerName string, fullScan bool, progress scanProgress) ([]discussionLink, []models.FetchFailure) {
+	providerName = strings.TrimSpace(strings.ToLower(providerName))
+	slot := s.slot(providerName)
+	slot.mu.Lock()
+	defer slot.mu.Unlock()
+
+	index := slot.index
+	if index == nil || index.Origin != utils.BaseURL() {
+		index = s.load(providerName)
+		slot.failures = nil
+	}
+
+	now := time.Now()
+	var failures []models.FetchFailure
+	switch {
+	case index != nil && cacheMode != CacheOnly && fullScan && now.Sub(index.ScannedAt) >= linkIndexFreshFor:
+		// Cached list pages may hold old reply counts too.
+		index, failures = s.scan(withRevalidation(ctx), providerName, progress)
+	case index != nil && (cacheMode == CacheOnly || now.Sub(index.UpdatedAt) < linkIndexFreshFor):
+		debugf("using link index for %s (%d links, updated %s ago)", providerName, len(index.Links), now.Sub(index.UpdatedAt).Round(time.Second))
+		// Failures of the scan that built this index in memory still apply.
+		failures = slot.failures
+	case index != nil && now.Sub(index.ScannedAt) < linkIndexRescanAfter:
+		failures = s.refresh(ctx, index, progress)
+	default:
+		index, failures = s.scan(ctx, providerName, progress)
+	}
+
+	// An interrupted scan is not kept, so the next caller starts over.
+	if ctx.Err() == nil {
+		slot.index, slot.failures = index, failures
+	}
+	return index.entries(), append([]models.FetchFailure(nil), failures...)
+}
+
+func (s *linkIndexStore) slot(providerName string) *linkIndexSlot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	slot, ok := s.slots[providerName]
+	if !ok {
+		slot = &linkIndexSlot{}
+		s.slots[providerName] = slot
+	}
+	return slot
+}
+
+// scan walks every list page of the provider. Only a scan without failures
+// is saved to disk.
+func (s *linkIndexStore) scan(ctx context.Context, providerName string, progress scanProgress) (*providerLinkIndex, []models.FetchFailure) {
+	debugf("scanning every discussion list page of %s", providerName)
+	numPages := getMaxNumPages(ctx, utils.AddToBaseUrl(fmt.Sprintf("/discussions/%s/", providerName)))
+	progress.add(numPages)
+	entries, failures := fetchAllPageLinksConcurrently(ctx, providerName, numPages, tuning.MaxConcurrentRequests, progress.done)
+
+	now := time.Now()
+	index := &providerLinkIndex{
+		Origin:    utils.BaseURL(),
+		Provider:  providerName,
+		Links:     entries,
+		ScannedAt: now,
+		UpdatedAt: now,
+	}
+	if len(failures) == 0 && ctx.Err() == nil {
+		s.save(index)
+	}
+	return index, failures
+}
+
+// refresh fetches list pages from the first one until a page holds only
+// links the index already has with the same reply count, then merges the
+// new and changed links in front of the rest.
+func (s *linkIndexStore) refresh(ctx context.Context, index *providerLinkIndex, progress scanProgress) []models.FetchFailure {
+	known := make(map[string]int, len(index.Links))
+	for _, link := range index.Links {
+		known[link.Link] = link.Replies
+	}
+
+	var fetched []indexedLink
+	var failures []models.FetchFailure
+	seen := map[string]struct{}{}
+	complete := false
+	pages, lastPage := 0, 1
+	for page := 1; page <= lastPage && ctx.Err() == nil; page++ {
+		pages++
+		progress.add(1)
+		url := discussionListPageURL(index.Provider, page)
+		entries, pageCount, err := fetchListPage(ctx, index.Provider, url)
+		progress.done()
+		if err != nil {
+			if ErrorKind(err) != ErrorKindCancelled {
+				failures = append(failures, newFailure(pageDiscussionList, url, err))
+			}
+			break
+		}
+		if page == 1 {
+			lastPage = pageCount
+		}
+
+		unchanged := true
+		for _, entry := range entries {
+			if _, dup := seen[entry.Link]; dup {
+				continue
+			}
+			seen[entry.Link] = struct{}{}
+			if replies, ok := known[entry.Link]; !ok || replies != entry.Replies {
+				unchanged = false
+			}
+			fetched = append(fetched, indexedLink{Link: entry.Link, Replies: entry.Replies, Page: page})
+		}
+		if unchanged || page == lastPage {
+			complete = true
+			break
+		}
+	}
+	debugf("refreshed link index for %s: %d list page(s) fetched, complete: %v", index.Provider, pages, complete)
+
+	merged := fetched
+	for _, link := range index.Links {
+		if _, ok := seen[link.Link]; !ok {
+			merged = append(merged, link)
+		}
+	}
+	index.Links = merged
+	if complete {
+		index.UpdatedAt = time.Now()
+	}
+	s.save(index)
+	return failures
+}
+
+// fetchListPage returns the discussion links of one list page and the total
+// number of list pages it shows.
+func fetchListPage(ctx context.Context, providerName, url string) ([]discussionLink, int, error) {
+	ctx = withReferer(ctx, utils.AddToBaseUrl(fmt.Sprintf("/discussions/%s/", providerName)))
+	doc, err := ParseHTML(ctx, url)
+	if err != nil {
+		return nil, 0, err
+	}
+	return extractDiscussionEntries(doc), pageCountFromDoc(doc), nil
+}
+
+func (s *linkIndexStore) pathFor(providerName string) string {
+	if s.dir == "" {
+		return ""
+	}
+	return filepath.Join(s.dir, providerName+".json")
+}
+
+func (s *linkIndexStore) load(providerName string) *providerLinkIndex {
+	path := s.pathFor(providerName)
+	if path == "" || cacheMode == CacheDisabled {
+		return nil
+	}
+
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var index providerLinkIndex
+	if err := json.Unmarshal(payload, &index); err != nil {
+		debugf("failed to parse link index %q: %v", path, err)
+		return nil
+	}
+	if index.Origin != utils.BaseURL() || index.Provider != providerName {
+		return nil
+	}
+	return &index
+}
+
+func (s *linkIndexStore) save(index *providerLinkIndex) {
+	path := s.pathFor(index.Provider)
+	if path == "" || cacheMode == CacheDisabled {
+		return
+	}
+
+	payload, err := json.Marshal(index)
+	if err != nil {
+		debugf("failed to marshal link index: %v", err)
+		return
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		debugf("failed to create link index dir %q: %v", s.dir, err)
+		return
+	}
+	if err := os.WriteFile(path, payload, 0o644); err != nil {
+		debugf("failed to write link index %q: %v", path, err)
+	}
+}
@@ -0,0 +1,137 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// acmeListPages renders discussion list pages for the fake provider "acme";
+// pages[i] holds the question numbers listed on page i+1.
+func acmeListPages(pages ...[]int) stubFetcher {
+	stub := stubFetcher{}
+	for i, questions := range pages {
+		var html strings.Builder
+		fmt.Fprintf(&html, `<div class="discussion-list-page-indicator">Page <strong>%d</strong> of <strong>%d</strong></div>`, i+1, len(pages))
+		for _, q := range questions {
+			fmt.Fprintf(&html, `<div class="discussion-row"><a href="/discussions/acme/view/%d-exam-ex-1-topic-1-question-%d-discussion/">Question %d</a>`+
+				`<span class="discussion-stats-replies">0</span></div>`, 1000+q, q, q)
+		}
+		stub[fmt.Sprintf("https://www.examtopics.com/discussions/acme/%d", i+1)] = html.String()
+		if i == 0 {
+			stub["https://www.examtopics.com/discussions/acme/"] = html.String()
+		}
+	}
+	return stub
+}
+
+func questionNumbers(entries []discussionLink) []int {
+	var out []int
+	for _, entry := range entries {
+		var id, q int
+		fmt.Sscanf(entry.Link, "/discussions/acme/view/%d-exam-ex-1-topic-1-question-%d-discussion/", &id, &q)
+		out = append(out, q)
+	}
+	return out
+}
+
+func TestLinkIndexIsReusedAcrossRuns(t *testing.T) {
+	useTempHTTPCache(t, CacheDefault)
+	useTuning(t, fastTuning())
+	counter := &countingFetcher{next: acmeListPages([]int{3, 2}, []int{1}), hits: map[string]int{}}
+	useFetcher(t, counter)
+	ctx := context.Background()
+
+	entries, failures := linkIndexes.examLinks(ctx, "acme", "ex-1", false, scanProgress{})
+	if len(failures) > 0 || !slices.Equal(questionNumbers(entries), []int{3, 2, 1}) {
+		t.Fatalf("first scan: got %v (failures %v)", questionNumbers(entries), failures)
+	}
+	if got := entries[2].ListPage; got != "https://www.examtopics.com/discussions/acme/2" {
+		t.Fatalf("ListPage of question 1: got %q", got)
+	}
+
+	// A new process loads the index from disk instead of scanning again.
+	linkIndexes = newLinkIndexStore(linkIndexes.dir)
+	counter.hits = map[string]int{}
+	entries, _ = linkIndexes.examLinks(ctx, "acme", "ex-1", false, scanProgress{})
+	if len(counter.hits) != 0 || len(entries) != 3 {
+		t.Fatalf("fresh index should need no requests, made %v and got %d links", counter.hits, len(entries))
+	}
+
+	if slugs := inferExamSlugsFromDiscussionPages(ctx, "acme"); !slices.Equal(slugs, []string{"ex-1"}) || len(counter.hits) != 0 {
+		t.Fatalf("discovery should read the index: slugs %v, requests %v", slugs, counter.hits)
+	}
+}
+
+func TestLinkIndexRefreshesFromFirstPageUntilKnownLinks(t *testing.T) {
+	useTempHTTPCache(t, CacheDefault)
+	useTuning(t, fastTuning())
+	counter := &countingFetcher{next: acmeListPages([]int{5, 4}, []int{3, 2}, []int{1}), hits: map[string]int{}}
+	useFetcher(t, counter)
+	ctx := context.Background()
+
+	linkIndexes.providerLinks(ctx, "acme", false, scanProgress{})
+	linkIndexes.slot("acme").index.UpdatedAt = time.Now().Add(-2 * linkIndexFreshFor)
+
+	// Question 6 is posted and pushes everything down by one.
+	counter.next = acmeListPages([]int{6, 5}, []int{4, 3}, []int{2, 1})
+	counter.hits = map[string]int{}
+	entries, failures := linkIndexes.providerLinks(ctx, "acme", false, scanProgress{})
+	if len(failures) > 0 || !slices.Equal(questionNumbers(entries), []int{6, 5, 4, 3, 2, 1}) {
+		t.Fatalf("refreshed index: got %v (failures %v)", questionNumbers(entries), failures)
+	}
+	if _, fetched := counter.hits["https://www.examtopics.com/discussions/acme/3"]; fetched || len(counter.hits) != 2 {
+		t.Fatalf("refresh should stop at the first page of known links, fetched %v", counter.hits)
+	}
+
+	stored := newLinkIndexStore(linkIndexes.dir).load("acme")
+	if stored == nil || len(stored.Links) != 6 || time.Since(stored.UpdatedAt) > time.Minute {
+		t.Fatalf("refreshed index should be saved, got %+v", stored)
+	}
+}
+
+func TestLinkIndexIsNotStoredWithCacheDisabled(t *testing.T) {
+	useTempHTTPCache(t, CacheDisabled)
+	useTuning(t, fastTuning())
+	useFetcher(t, acmeListPages([]int{1}))
+
+	linkIndexes.providerLinks(context.Background(), "acme", false, scanProgress{})
+	cacheMode = CacheDefault
+	if stored := linkIndexes.load("acme"); stored != nil {
+		t.Fatalf("--no-cache must not write %s", filepath.Join(linkIndexes.dir, "acme.json"))
+	}
+}
+
+func TestLinkIndexFullScanPicksUpRepliesOnLaterPages(t *testing.T) {
+	useTempHTTPCache(t, CacheDefault)
+	useTuning(t, fastTuning())
+	counter := &countingFetcher{next: acmeListPages([]int{3, 2}, []int{1}), hits: map[string]int{}}
+	useFetcher(t, counter)
+	ctx := context.Background()
+
+	linkIndexes.providerLinks(ctx, "acme", false, scanProgress{})
+	index := linkIndexes.slot("acme").index
+	index.UpdatedAt = time.Now().Add(-2 * linkIndexFreshFor)
+	index.ScannedAt = index.UpdatedAt
+
+	// Question 1, on the last page, gets new comments.
+	pages := acmeListPages([]int{3, 2}, []int{1})
+	last := "https://www.examtopics.com/discussions/acme/2"
+	pages[last] = strings.Replace(pages[last], `replies">0<`, `replies">4<`, 1)
+	counter.next = pages
+
+	entries, _ := linkIndexes.providerLinks(ctx, "acme", false, scanProgress{})
+	if entries[2].Replies != 0 {
+		t.Fatalf("a refresh stops at the unchanged first page, got %d replies", entries[2].Replies)
+	}
+	linkIndexes.slot("acme").index.UpdatedAt = time.Now().Add(-2 * linkIndexFreshFor)
+
+	entries, failures := linkIndexes.providerLinks(ctx, "acme", true, scanProgress{})
+	if len(failures) > 0 || entries[2].Replies != 4 {
+		t.Fatalf("a full scan should read every list page: got %d replies (failures %v)", entries[2].Replies, failures)
+	}
+}
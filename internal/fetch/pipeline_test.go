@@ -0,0 +1,231 @@
+package fetch
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"examtopics-downloader/internal/models"
+	"examtopics-downloader/internal/utils"
+)
+
+// startReplay serves a fixture archive from testdata and routes all fetches
+// to it, with the on-disk caches out of the way.
+func startReplay(t *testing.T, name string) *ReplayServer {
+	t.Helper()
+	useTempHTTPCache(t, CacheDisabled)
+	useTuning(t, fastTuning())
+
+	archive, err := LoadFixtureArchive(filepath.Join("testdata", "fixtures", name))
+	if err != nil {
+		t.Fatalf("LoadFixtureArchive: %v", err)
+	}
+	server := NewReplayServer(archive)
+	t.Cleanup(server.Close)
+	useFetcher(t, server.Fetcher())
+
+	previousOutput := statusOutput
+	SetOutput(io.Discard)
+	t.Cleanup(func() { statusOutput = previousOutput })
+	return server
+}
+
+func TestPipelineAgainstReplayedFixtures(t *testing.T) {
+	server := startReplay(t, "cisco-200-301.json")
+	ctx := context.Background()
+
+	providers := GetAllProviders(ctx)
+	if !slices.Equal(providers, []string{"amazon", "cisco"}) {
+		t.Fatalf("GetAllProviders: want [amazon cisco], got %v", providers)
+	}
+
+	exams := GetProviderExamSlugs(ctx, "cisco")
+	if !slices.Equal(exams, []string{"200-301", "350-401"}) {
+		t.Fatalf("GetProviderExamSlugs: want [200-301 350-401], got %v", exams)
+	}
+
+	questions := GetAllPages(ctx, "cisco", "200-301")
+	if len(questions) != 2 {
+		t.Fatalf("GetAllPages: want 2 questions, got %d", len(questions))
+	}
+	if got := questions[0].Answer; got != "B" {
+		t.Fatalf("question 1 answer: want %q, got %q", "B", got)
+	}
+	if got := len(questions[0].Comments); got != 2 {
+		t.Fatalf("question 1 comments: want 2, got %d", got)
+	}
+	if got := questions[1].Content; !strings.Contains(got, "administrative distance of OSPF") {
+		t.Fatalf("question 2 content: got %q", got)
+	}
+	if got := questions[1].ContentHTML; got != "<p>What is the default administrative distance of OSPF?</p>" {
+		t.Fatalf("question 2 HTML should hold only the question paragraph, got %q", got)
+	}
+	if got := questions[0].Type; got != models.QuestionTypeMultipleChoice {
+		t.Fatalf("question 1 type: want %q, got %q", models.QuestionTypeMultipleChoice, got)
+	}
+	if q := questions[1]; q.Topic != 1 || q.QuestionNumber != 2 || q.ExamSlug != "200-301" {
+		t.Fatalf("question 2 position: want topic 1, number 2, exam 200-301, got %d, %d, %q", q.Topic, q.QuestionNumber, q.ExamSlug)
+	}
+
+	outputPath := filepath.Join(t.TempDir(), "cisco_200-301.html")
+	files, err := utils.WriteDataWithSelection(questions, outputPath, true, "cisco", "200-301")
+	if err != nil {
+		t.Fatalf("WriteDataWithSelection: %v", err)
+	}
+	rendered, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("reading rendered HTML: %v", err)
+	}
+	for _, want := range []string{"Which protocol operates at the transport layer?", "OSPF is 110."} {
+		if !strings.Contains(string(rendered), want) {
+			t.Fatalf("rendered HTML is missing %q", want)
+		}
+	}
+
+	if misses := server.Misses(); len(misses) > 0 {
+		t.Fatalf("pipeline requested pages missing from the fixture: %v", misses)
+	}
+}
+
+func TestRecordingFetcherRoundTripsThroughArchive(t *testing.T) {
+	startReplay(t, "cisco-200-301.json")
+
+	recorder := NewRecordingFetcher(fetcher)
+	useFetcher(t, recorder)
+	getMaxNumPages(context.Background(), "https://www.examtopics.com/discussions/cisco/")
+	if _, err := ParseHTML(context.Background(), "https://www.examtopics.com/missing/"); err == nil {
+		t.Fatal("expected an error for a page missing from the fixture")
+	}
+
+	path := filepath.Join(t.TempDir(), "recorded.json")
+	if err := recorder.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	archive, err := LoadFixtureArchive(path)
+	if err != nil {
+		t.Fatalf("LoadFixtureArchive: %v", err)
+	}
+	if len(archive.Entries) != 1 || archive.Entries[0].URL != "https://www.examtopics.com/discussions/cisco/" {
+		t.Fatalf("expected only the successful fetch to be recorded, got %+v", archive.Entries)
+	}
+}
+
+func TestPipelineReportsPagesThatFailed(t *testing.T) {
+	useTempHTTPCache(t, CacheDisabled)
+	useTuning(t, fastTuning())
+
+	archive, err := LoadFixtureArchive(filepath.Join("testdata", "fixtures", "cisco-200-301.json"))
+	if err != nil {
+		t.Fatalf("LoadFixtureArchive: %v", err)
+	}
+	missing := "https://www.examtopics.com/discussions/cisco/view/101002-exam-200-301-topic-1-question-2-discussion/"
+	archive.Entries = slices.DeleteFunc(archive.Entries, func(entry FixtureEntry) bool {
+		return entry.URL == missing
+	})
+	server := NewReplayServer(archive)
+	defer server.Close()
+	useFetcher(t, server.Fetcher())
+
+	previousOutput := statusOutput
+	SetOutput(io.Discard)
+	t.Cleanup(func() { statusOutput = previousOutput })
+
+	result := GetAllPagesWithOptions(context.Background(), "cisco", "200-301", ExtractOptions{})
+	if len(result.Questions) != 1 {
+		t.Fatalf("want 1 question, got %d", len(result.Questions))
+	}
+	if len(result.Failures) != 1 {
+		t.Fatalf("want 1 failure, got %+v", result.Failures)
+	}
+	failure := result.Failures[0]
+	if failure.URL != missing || failure.Page != pageQuestion || failure.Kind != string(ErrorKindHTTPStatus) || failure.Status != 404 {
+		t.Fatalf("unexpected failure: %+v", failure)
+	}
+}
+
+func TestIncrementalKeepsQuestionsWhenAListPageFails(t *testing.T) {
+	useTempHTTPCache(t, CacheDisabled)
+	useTuning(t, fastTuning())
+
+	archive, err := LoadFixtureArchive(filepath.Join("testdata", "fixtures", "cisco-200-301.json"))
+	if err != nil {
+		t.Fatalf("LoadFixtureArchive: %v", err)
+	}
+	archive.Entries = slices.DeleteFunc(archive.Entries, func(entry FixtureEntry) bool {
+		return entry.URL == "https://www.examtopics.com/discussions/cisco/1"
+	})
+	server := NewReplayServer(archive)
+	defer server.Close()
+	useFetcher(t, server.Fetcher())
+
+	previousOutput := statusOutput
+	SetOutput(io.Discard)
+	t.Cleanup(func() { statusOutput = previousOutput })
+
+	previous := []models.QuestionData{
+		{QuestionLink: "/discussions/cisco/view/101001-exam-200-301-topic-1-question-1-discussion/", Answer: "B"},
+		{QuestionLink: "/discussions/cisco/view/101002-exam-200-301-topic-1-question-2-discussion/", Answer: "C"},
+	}
+	result := GetAllPagesWithOptions(context.Background(), "cisco", "200-301", ExtractOptions{Previous: previous})
+	if len(result.Failures) == 0 {
+		t.Fatal("expected the list page failure to be reported")
+	}
+	if result.Removed != 0 || len(result.Questions) != 2 {
+		t.Fatalf("a failed list page must not remove questions: removed %d, kept %d", result.Removed, len(result.Questions))
+	}
+	if result.Questions[0].Answer != "B" || result.Questions[1].Answer != "C" {
+		t.Fatalf("expected the previous questions in order, got %+v", result.Questions)
+	}
+}
+
+// countingFetcher counts requests per URL before passing them on.
+type countingFetcher struct {
+	next Fetcher
+
+	mu   sync.Mutex
+	hits map[string]int
+}
+
+func (c *countingFetcher) Get(ctx context.Context, url string) ([]byte, error) {
+	c.mu.Lock()
+	c.hits[url]++
+	c.mu.Unlock()
+	return c.next.Get(ctx, url)
+}
+
+func TestConcurrentExtractionsScanEachProviderOnce(t *testing.T) {
+	server := startReplay(t, "cisco-200-301.json")
+	counter := &countingFetcher{next: server.Fetcher(), hits: map[string]int{}}
+	useFetcher(t, counter)
+
+	ResetStats()
+	var wg sync.WaitGroup
+	results := make([]ExtractResult, 2)
+	for i, exam := range []string{"200-301", "350-401"} {
+		wg.Add(1)
+		go func(i int, exam string) {
+			defer wg.Done()
+			results[i] = GetAllPagesWithOptions(context.Background(), "cisco", exam, ExtractOptions{KeepStats: true, Quiet: true})
+		}(i, exam)
+	}
+	wg.Wait()
+
+	if got := len(results[0].Questions); got != 2 {
+		t.Fatalf("200-301: want 2 questions, got %d", got)
+	}
+	// The fixture lists a 350-401 discussion without serving its page.
+	if got := len(results[1].Failures); got != 1 || !strings.Contains(results[1].Failures[0].URL, "350-401") {
+		t.Fatalf("350-401: want one failed question page, got %+v", results[1].Failures)
+	}
+
+	for url, hits := range counter.hits {
+		if strings.Contains(url, "/discussions/cisco/") && !strings.Contains(url, "/view/") && hits != 1 {
+			t.Fatalf("list page %s fetched %d times, want once for both extractions", url, hits)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"strings"
 	"testing"
 
+	"examtopics-downloader/internal/utils"
+
 	"github.com/PuerkitoBio/goquery"
 )
 
@@ -281,3 +283,51 @@ func TestNormalizeDiscussionViewHref(t *testing.T) {
 		}
 	}
 }
+
+func TestNormalizeDiscussionViewHrefAcceptsConfiguredOrigin(t *testing.T) {
+	if err := utils.SetBaseURL("http://127.0.0.1:8080"); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+	t.Cleanup(func() { utils.SetBaseURL("") })
+
+	link := "/discussions/cisco/view/1-exam-200-301-topic-1-question-1-discussion/"
+	for _, input := range []string{"http://127.0.0.1:8080" + link, "https://www.examtopics.com" + link} {
+		if got := normalizeDiscussionViewHref(input); got != link {
+			t.Fatalf("normalizeDiscussionViewHref(%q): want %q, got %q", input, link, got)
+		}
+	}
+	if got := normalizeDiscussionViewHref("http://127.0.0.1:9090" + link); got != "" {
+		t.Fatalf("expected links to other hosts to be rejected, got %q", got)
+	}
+}
+
+func TestExtractDiscussionEntriesReadsReplyCounts(t *testing.T) {
+	html := `
+<div class="row discussion-row">
+  <a class="discussion-link" href="/discussions/cisco/view/1-exam-200-301-topic-1-question-1-discussion/">Q1</a>
+  <span class="discussion-stats-replies">14</span>
+</div>
+<div class="row discussion-row">
+  <a class="discussion-link" href="/discussions/cisco/view/2-exam-200-301-topic-1-question-2-discussion/">Q2</a>
+</div>
+<a href="/discussions/cisco/view/1-exam-200-301-topic-1-question-1-discussion/">duplicate</a>`
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("failed to parse html: %v", err)
+	}
+
+	got := extractDiscussionEntries(doc)
+	want := []discussionLink{
+		{Link: "/discussions/cisco/view/1-exam-200-301-topic-1-question-1-discussion/", Replies: 14},
+		{Link: "/discussions/cisco/view/2-exam-200-301-topic-1-question-2-discussion/", Replies: -1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d entries, got %d (%v)", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("unexpected entry at index %d: want %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
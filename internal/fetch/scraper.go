@@ -1,15 +1,18 @@
 package fetch
 
 import (
+	"context"
 	"fmt"
 	"html"
+	"io"
 	"net/url"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
-	"examtopics-downloader/internal/constants"
 	"examtopics-downloader/internal/models"
 	"examtopics-downloader/internal/utils"
 
@@ -17,11 +20,13 @@ import (
 	"github.com/cheggaaa/pb/v3"
 )
 
-func getDataFromLink(link string) *models.QuestionData {
-	doc, err := ParseHTML(link, *client)
+// questionPageSelector matches the content every question page carries.
+const questionPageSelector = ".card-text, li.multi-choice-item"
+
+func getDataFromLink(ctx context.Context, link string) (*models.QuestionData, error) {
+	doc, err := parseHTMLExpecting(ctx, link, questionPageSelector)
 	if err != nil {
-		debugf("failed parsing HTML data from link: %v", err)
-		return nil
+		return nil, err
 	}
 
 	var allQuestions []string
@@ -30,21 +35,73 @@ func getDataFromLink(link string) *models.QuestionData {
 	})
 
 	answer := strings.TrimSpace(doc.Find(".correct-answer").Text())
+	header := strings.ReplaceAll(strings.TrimSpace(doc.Find(".question-discussion-header").Text()), "\t", "")
+	topic, number := questionPosition(header, link)
+
+	data := &models.QuestionData{
+		Title:           utils.CleanText(doc.Find("h1").Text()),
+		Header:          header,
+		Content:         utils.CleanText(doc.Find(".card-text").Text()),
+		ContentHTML:     extractQuestionHTML(doc),
+		ExhibitURLs:     extractExhibitImageURLs(doc),
+		Questions:       allQuestions,
+		Answer:          answer,
+		Timestamp:       utils.CleanText(doc.Find(".discussion-meta-data > i").Text()),
+		QuestionLink:    link,
+		Comments:        extractDiscussionComments(doc),
+		Topic:           topic,
+		QuestionNumber:  number,
+		ExamSlug:        extractExamSlugFromDiscussionURL(link),
+		AnswerImageURLs: extractAnswerImageURLs(doc),
+		Votes:           extractVoteDistribution(doc),
+	}
+	data.Type = utils.ClassifyQuestion(*data)
+	return data, nil
+}
+
+// extractQuestionHTML returns the question body as sanitized HTML. The
+// suggested answer paragraph shares the .card-text class and is left out.
+func extractQuestionHTML(doc *goquery.Document) string {
+	var fragment strings.Builder
+	doc.Find(".card-text").Not(".question-answer").Each(func(i int, s *goquery.Selection) {
+		if outer, err := goquery.OuterHtml(s); err == nil {
+			fragment.WriteString(outer)
+		}
+	})
+	return utils.SanitizeHTML(fragment.String())
+}
 
-	return &models.QuestionData{
-		Title:        utils.CleanText(doc.Find("h1").Text()),
-		Header:       strings.ReplaceAll(strings.TrimSpace(doc.Find(".question-discussion-header").Text()), "\t", ""),
-		Content:      utils.CleanText(doc.Find(".card-text").Text()),
-		ExhibitURLs:  extractExhibitImageURLs(doc),
-		Questions:    allQuestions,
-		Answer:       answer,
-		Timestamp:    utils.CleanText(doc.Find(".discussion-meta-data > i").Text()),
-		QuestionLink: link,
-		Comments:     extractDiscussionComments(doc),
+var (
+	headerTopicPattern    = regexp.MustCompile(`(?i)Topic\s*#:?\s*(\d+)`)
+	headerQuestionPattern = regexp.MustCompile(`(?i)Question\s*#:?\s*(\d+)`)
+)
+
+// questionPosition reads "Topic #: 2" and "Question #: 5" off the question
+// header, falling back to the numbers in the discussion link.
+func questionPosition(header, link string) (topic, number int) {
+	topic, number = utils.QuestionPositionFromLink(link)
+	if m := headerTopicPattern.FindStringSubmatch(header); len(m) == 2 {
+		topic, _ = strconv.Atoi(m[1])
+	}
+	if m := headerQuestionPattern.FindStringSubmatch(header); len(m) == 2 {
+		number, _ = strconv.Atoi(m[1])
 	}
+	return topic, number
 }
 
+// extractExhibitImageURLs returns the images of the question body, such as
+// exhibits and the answer area of hotspot and drag-and-drop questions.
 func extractExhibitImageURLs(doc *goquery.Document) []string {
+	return collectImageURLs(doc.Find(".card-text").Not(".question-answer").Find("img"))
+}
+
+// extractAnswerImageURLs returns the images of the suggested answer, which
+// hotspot and drag-and-drop questions show instead of answer letters.
+func extractAnswerImageURLs(doc *goquery.Document) []string {
+	return collectImageURLs(doc.Find(".question-answer img, .correct-answer img"))
+}
+
+func collectImageURLs(images *goquery.Selection) []string {
 	var urls []string
 	seen := map[string]struct{}{}
 
@@ -60,7 +117,7 @@ func extractExhibitImageURLs(doc *goquery.Document) []string {
 		urls = append(urls, normalized)
 	}
 
-	doc.Find(".card-text img").Each(func(i int, s *goquery.Selection) {
+	images.Each(func(i int, s *goquery.Selection) {
 		if src, ok := s.Attr("src"); ok {
 			add(src)
 		}
@@ -90,7 +147,7 @@ func normalizeExhibitURL(raw string) string {
 	if strings.HasPrefix(raw, "//") {
 		raw = "https:" + raw
 	} else if strings.HasPrefix(raw, "/") {
-		raw = "https://www.examtopics.com" + raw
+		raw = utils.AddToBaseUrl(raw)
 	}
 
 	u, err := url.Parse(raw)
@@ -122,40 +179,112 @@ func firstURLFromSrcset(srcset string) string {
 	return parts[0]
 }
 
+// extractDiscussionComments returns every comment of the discussion in page
+// order, replies included. A reply records the ID of the comment it answers
+// in ParentID.
 func extractDiscussionComments(doc *goquery.Document) []models.CommentData {
 	var comments []models.CommentData
 	answerLetterPattern := regexp.MustCompile(`\b([A-F])\b`)
 
-	doc.Find(".discussion-container .comment-container").Each(func(i int, s *goquery.Selection) {
-		user := strings.TrimSpace(s.Find(".comment-username").First().Text())
+	containers := doc.Find(".discussion-container .comment-container")
+	containers.Each(func(i int, s *goquery.Selection) {
+		user := strings.TrimSpace(ownCommentPart(s, ".comment-username").First().Text())
 		if user == "" {
 			user = "Anonymous"
 		}
 
 		answer := ""
-		answerText := strings.TrimSpace(s.Find(".comment-selected-answers strong").First().Text())
+		selected := ownCommentPart(s, ".comment-selected-answers").First()
+		answerText := strings.TrimSpace(selected.Find("strong").First().Text())
 		if answerText == "" {
-			answerText = strings.TrimSpace(s.Find(".comment-selected-answers").First().Text())
+			answerText = strings.TrimSpace(selected.Text())
 		}
 		if m := answerLetterPattern.FindStringSubmatch(strings.ToUpper(answerText)); len(m) == 2 {
 			answer = m[1]
 		}
 
-		content := normalizeCommentText(s.Find(".comment-content").First().Text())
+		content := normalizeCommentText(ownCommentPart(s, ".comment-content").First().Text())
 		if content == "" {
 			return
 		}
 
-		comments = append(comments, models.CommentData{
-			User:   user,
-			Answer: answer,
-			Text:   content,
+		comment := models.CommentData{
+			ID:      commentID(containers, s),
+			User:    user,
+			Answer:  answer,
+			Text:    content,
+			Date:    commentDate(ownCommentPart(s, ".comment-date").First()),
+			Upvotes: commentUpvotes(ownCommentPart(s, ".upvote-count").First().Text()),
+		}
+		if parent := s.Parent().Closest(".comment-container"); parent.Length() > 0 {
+			comment.ParentID = commentID(containers, parent)
+		}
+		ownCommentPart(s, ".badge").Each(func(_ int, badge *goquery.Selection) {
+			switch strings.ToLower(strings.TrimSpace(badge.Text())) {
+			case "highly voted":
+				comment.HighlyVoted = true
+			case "most recent":
+				comment.MostRecent = true
+			}
 		})
+		comments = append(comments, comment)
 	})
 
 	return comments
 }
 
+// ownCommentPart finds the elements of a comment, leaving out those of its
+// replies, which are nested inside it.
+func ownCommentPart(comment *goquery.Selection, selector string) *goquery.Selection {
+	own := comment.Get(0)
+	return comment.Find(selector).FilterFunction(func(_ int, s *goquery.Selection) bool {
+		return s.Closest(".comment-container").Get(0) == own
+	})
+}
+
+// commentID returns the ExamTopics ID of a comment. Comments without one
+// are numbered by their position among all comments of the page.
+func commentID(all, s *goquery.Selection) string {
+	if id := strings.TrimSpace(s.AttrOr("data-comment-id", "")); id != "" {
+		return id
+	}
+	if id := strings.TrimPrefix(strings.TrimSpace(s.AttrOr("id", "")), "comment-"); id != "" {
+		return id
+	}
+	return "c" + strconv.Itoa(all.IndexOfSelection(s)+1)
+}
+
+var commentDateLayouts = []string{
+	"Mon 02 Jan 2006 15:04",
+	"Mon 2 Jan 2006 15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+	"Jan 2, 2006, 3:04 p.m.",
+}
+
+// commentDate prefers the absolute date in the title of .comment-date over
+// its relative text ("1 year, 2 months ago").
+func commentDate(s *goquery.Selection) string {
+	raw := strings.TrimSpace(s.AttrOr("title", ""))
+	if raw == "" {
+		raw = strings.TrimSpace(s.Text())
+	}
+	raw = strings.Join(strings.Fields(raw), " ")
+	for _, layout := range commentDateLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t.UTC().Format(time.RFC3339)
+		}
+	}
+	return raw
+}
+
+var upvoteCountPattern = regexp.MustCompile(`\d+`)
+
+func commentUpvotes(raw string) int {
+	n, _ := strconv.Atoi(upvoteCountPattern.FindString(raw))
+	return n
+}
+
 func normalizeCommentText(raw string) string {
 	raw = strings.ReplaceAll(raw, "\r\n", "\n")
 	raw = strings.ReplaceAll(raw, "\r", "\n")
@@ -177,25 +306,33 @@ func normalizeCommentText(raw string) string {
 	return strings.Join(cleaned, "\n")
 }
 
-func fetchAllPageLinksConcurrently(providerName, selectedExam string, numPages, concurrency int, onPageProcessed func()) []string {
+// listPageResult is the outcome of scanning one discussion list page.
+type listPageResult struct {
+	page  int
+	url   string
+	links []discussionLink
+	err   error
+}
+
+// fetchAllPageLinksConcurrently scans every list page of the provider and
+// returns the links in list order, each with the page it was found on.
+func fetchAllPageLinksConcurrently(ctx context.Context, providerName string, numPages, concurrency int, onPageProcessed func()) ([]indexedLink, []models.FetchFailure) {
 	var wg sync.WaitGroup
 	sem := make(chan struct{}, concurrency)
-	results := make(chan []string, numPages)
-
-	rateLimiter := utils.CreateRateLimiter(constants.RequestsPerSecond)
-	defer rateLimiter.Stop()
+	results := make(chan listPageResult, numPages)
 
 	for i := 1; i <= numPages; i++ {
 		wg.Add(1)
 		go func(i int) {
 			defer wg.Done()
-			sem <- struct{}{}
+			if !acquireWorkerSlot(ctx, sem) {
+				return
+			}
 			defer func() { <-sem }()
 
-			<-rateLimiter.C
-
-			url := fmt.Sprintf("https://www.examtopics.com/discussions/%s/%d", providerName, i)
-			results <- getLinksFromPage(providerName, url, selectedExam)
+			url := discussionListPageURL(providerName, i)
+			links, _, err := fetchListPage(ctx, providerName, url)
+			results <- listPageResult{page: i, url: url, links: links, err: err}
 			if onPageProcessed != nil {
 				onPageProcessed()
 			}
@@ -207,78 +344,316 @@ func fetchAllPageLinksConcurrently(providerName, selectedExam string, numPages,
 		close(results)
 	}()
 
-	// about 10 questions per examtopics page, we can preallocate
-	all := make([]string, 0, numPages*10)
+	byPage := make([][]discussionLink, numPages+1)
+	var failures []models.FetchFailure
 	for res := range results {
-		all = append(all, res...)
+		if res.err != nil {
+			if ErrorKind(res.err) != ErrorKindCancelled {
+				failures = append(failures, newFailure(pageDiscussionList, res.url, res.err))
+			}
+			continue
+		}
+		byPage[res.page] = res.links
+	}
+
+	// about 10 questions per examtopics page, we can preallocate
+	all := make([]indexedLink, 0, numPages*10)
+	seen := map[string]struct{}{}
+	for page, links := range byPage {
+		for _, link := range links {
+			if _, dup := seen[link.Link]; dup {
+				continue
+			}
+			seen[link.Link] = struct{}{}
+			all = append(all, indexedLink{Link: link.Link, Replies: link.Replies, Page: page})
+		}
 	}
 
-	return all
+	sort.Slice(failures, func(i, j int) bool {
+		return failures[i].URL < failures[j].URL
+	})
+	return all, failures
+}
+
+// ExtractOptions tunes GetAllPagesWithOptions.
+type ExtractOptions struct {
+	// Checkpoint, when set, journals every finished question and supplies
+	// questions completed by an earlier run so they are not fetched again.
+	Checkpoint *Checkpoint
+	// Previous holds the questions of an earlier dataset for an incremental
+	// refresh: links already in it are reused instead of fetched, and links
+	// no longer listed on the site are dropped once every list page was
+	// scanned.
+	Previous []models.QuestionData
+	// RefreshChanged refetches previous questions whose reply count on the
+	// discussion list no longer matches the number of stored comments.
+	RefreshChanged bool
+	// KeepStats leaves the throttling counters and the expired-session flag
+	// as earlier extractions left them instead of resetting them, so the
+	// jobs of a batch add up; the batch calls ResetStats once up front.
+	KeepStats bool
+	// Quiet hides the progress bar and status lines, for extractions that
+	// run side by side.
+	Quiet bool
 }
 
+// ExtractResult is the outcome of GetAllPagesWithOptions.
+type ExtractResult struct {
+	Questions []models.QuestionData
+	// Resumed counts questions taken from the checkpoint instead of fetched.
+	Resumed int
+	// Added, Removed, Updated and Unchanged summarize an incremental refresh
+	// against ExtractOptions.Previous.
+	Added     int
+	Removed   int
+	Updated   int
+	Unchanged int
+	// Partial is set when the context was cancelled before every page was
+	// processed; Questions then holds only what finished in time.
+	Partial bool
+	// Throttle counts the throttling responses seen during this extraction.
+	Throttle ThrottleStats
+	// Failures lists every discussion list or question page that could not
+	// be fetched, in link order. Pages skipped because of cancellation are
+	// not included.
+	Failures []models.FetchFailure
+	// SessionExpired is set when imported session cookies stopped working
+	// and pages came back logged out.
+	SessionExpired bool
+}
+
+// How each question of an extraction was obtained.
+const (
+	sourceFetched = iota
+	sourceCheckpoint
+	sourcePrevious
+	sourceRefetched
+)
+
 // Main concurrent page scraping logic
-func GetAllPages(providerName string, selectedExam string) []models.QuestionData {
-	baseURL := fmt.Sprintf("https://www.examtopics.com/discussions/%s/", providerName)
-	numPages := getMaxNumPages(baseURL)
-	startTime := utils.StartTime()
-	bar := pb.StartNew(numPages)
+func GetAllPages(ctx context.Context, providerName string, selectedExam string) []models.QuestionData {
+	return GetAllPagesWithOptions(ctx, providerName, selectedExam, ExtractOptions{}).Questions
+}
+
+func GetAllPagesWithOptions(ctx context.Context, providerName string, selectedExam string, opts ExtractOptions) ExtractResult {
+	var result ExtractResult
+	if !opts.KeepStats {
+		ResetStats()
+	}
+	say := statusf
+	bar := pb.New(0)
+	if opts.Quiet {
+		say = func(string, ...any) {}
+		bar.SetWriter(io.Discard)
+	}
 
-	allLinks := fetchAllPageLinksConcurrently(providerName, selectedExam, numPages, constants.MaxConcurrentRequests, func() {
-		bar.Increment()
+	startTime := utils.StartTime()
+	bar.Start()
+	allEntries, listFailures := linkIndexes.examLinks(ctx, providerName, selectedExam, opts.RefreshChanged, scanProgress{
+		addPages: func(n int) { bar.AddTotal(int64(n)) },
+		pageDone: func() { bar.Increment() },
 	})
+	// Only a scan that saw every list page proves a question was removed.
+	scanComplete := len(listFailures) == 0 && ctx.Err() == nil
+
+	replyCounts := make(map[string]int, len(allEntries))
+	listPages := make(map[string]string, len(allEntries))
+	allLinks := make([]string, 0, len(allEntries))
+	for _, entry := range allEntries {
+		replyCounts[entry.Link] = entry.Replies
+		if _, ok := listPages[entry.Link]; !ok {
+			listPages[entry.Link] = entry.ListPage
+		}
+		allLinks = append(allLinks, entry.Link)
+	}
 
 	unique := utils.DeduplicateLinks(allLinks)
 	sortedLinks := utils.SortLinksByQuestionNumber(unique)
 	if summary := buildSelectedExamVariantSummary(providerName, selectedExam, sortedLinks); summary != "" {
-		fmt.Printf("\n%s\n", summary)
+		say("\n%s\n", summary)
 	}
-	bar.SetTotal(int64(numPages + len(sortedLinks)))
+	bar.AddTotal(int64(len(sortedLinks)))
+	result.Failures = listFailures
 
-	if len(sortedLinks) == 0 {
+	if len(sortedLinks) == 0 && (opts.Previous == nil || scanComplete) {
 		bar.Finish()
-		fmt.Println("No matching questions were found.")
-		return nil
+		result.Partial = ctx.Err() != nil
+		result.Throttle = limiter.Stats()
+		result.SessionExpired = sessionExpired.Load()
+		say("No matching questions were found.\n")
+		return result
 	}
 
+	previous := indexQuestionsByLink(opts.Previous)
+
 	var wg sync.WaitGroup
-	sem := make(chan struct{}, constants.MaxConcurrentRequests)
+	sem := make(chan struct{}, tuning.MaxConcurrentRequests)
 	results := make([]*models.QuestionData, len(sortedLinks))
-
-	rateLimiter := utils.CreateRateLimiter(constants.RequestsPerSecond)
-	defer rateLimiter.Stop()
+	sources := make([]int, len(sortedLinks))
+	fetchErrs := make([]error, len(sortedLinks))
 
 	for i, link := range sortedLinks {
+		if opts.Checkpoint != nil {
+			if data, ok := opts.Checkpoint.Lookup(link); ok {
+				results[i] = &data
+				sources[i] = sourceCheckpoint
+				bar.Increment()
+				continue
+			}
+		}
+
+		var fallback *models.QuestionData
+		if prev, ok := previous[link]; ok {
+			replies := replyCounts[link]
+			if !opts.RefreshChanged || replies < 0 || replies == len(prev.Comments) {
+				results[i] = &prev
+				sources[i] = sourcePrevious
+				bar.Increment()
+				continue
+			}
+			// Keep the stored copy if the refetch fails.
+			fallback = &prev
+			sources[i] = sourceRefetched
+		}
+
 		wg.Add(1)
 		url := utils.AddToBaseUrl(link)
 
-		go func(i int, url string) {
+		go func(i int, link, url string, fallback *models.QuestionData) {
 			defer wg.Done()
-			sem <- struct{}{}
+			defer bar.Increment()
+			if !acquireWorkerSlot(ctx, sem) {
+				if fallback != nil {
+					results[i] = fallback
+					sources[i] = sourcePrevious
+				}
+				return
+			}
 			defer func() { <-sem }()
 
-			<-rateLimiter.C
-
-			data := getDataFromLink(url)
+			fetchCtx := withReferer(ctx, listPages[link])
+			if fallback != nil {
+				// The cached page predates the new comments.
+				fetchCtx = withRevalidation(fetchCtx)
+			}
+			data, err := getDataFromLink(fetchCtx, url)
+			fetchErrs[i] = err
 			if data != nil {
 				results[i] = data
+				if opts.Checkpoint != nil {
+					if err := opts.Checkpoint.Record(link, *data); err != nil {
+						debugf("failed to journal %s: %v", link, err)
+					}
+				}
+			} else if fallback != nil {
+				results[i] = fallback
+				sources[i] = sourcePrevious
 			}
-			bar.Increment()
-		}(i, url)
+		}(i, link, url, fallback)
 	}
 
 	wg.Wait()
 	bar.Finish()
+	result.Partial = ctx.Err() != nil
 	// Filter out nil entries
-	var finalData []models.QuestionData
-	for _, entry := range results {
-		if entry != nil {
-			finalData = append(finalData, *entry)
+	for i, entry := range results {
+		if err := fetchErrs[i]; err != nil && ErrorKind(err) != ErrorKindCancelled {
+			failure := newFailure(pageQuestion, utils.AddToBaseUrl(sortedLinks[i]), err)
+			failure.KeptPrevious = entry != nil
+			result.Failures = append(result.Failures, failure)
+		}
+		if entry == nil {
+			continue
+		}
+		result.Questions = append(result.Questions, *entry)
+
+		switch sources[i] {
+		case sourceCheckpoint:
+			result.Resumed++
+		case sourcePrevious:
+			result.Unchanged++
+		case sourceRefetched:
+			result.Updated++
+		case sourceFetched:
+			if opts.Previous != nil {
+				result.Added++
+			}
+		}
+	}
+
+	if opts.Previous != nil {
+		listed := make(map[string]struct{}, len(sortedLinks))
+		for _, link := range sortedLinks {
+			listed[link] = struct{}{}
+		}
+		kept := 0
+		for _, question := range opts.Previous {
+			link := normalizeDiscussionViewHref(question.QuestionLink)
+			if _, ok := listed[link]; ok || link == "" {
+				continue
+			}
+			if scanComplete {
+				result.Removed++
+				continue
+			}
+			// The link may be on a list page that failed: keep the question.
+			listed[link] = struct{}{}
+			result.Questions = append(result.Questions, question)
+			result.Unchanged++
+			kept++
+		}
+		if kept > 0 {
+			utils.SortQuestions(result.Questions)
 		}
 	}
 
-	fmt.Printf("Extraction complete in %s.\n", utils.TimeSince(startTime))
+	result.Throttle = limiter.Stats()
+	result.SessionExpired = sessionExpired.Load()
+	if summary := formatThrottleSummary(result.Throttle); summary != "" {
+		say("%s\n", summary)
+	}
+	if result.Resumed > 0 {
+		say("Resumed %d question(s) from checkpoint.\n", result.Resumed)
+	}
+	if opts.Previous != nil {
+		say("Incremental refresh: %d added, %d removed, %d updated, %d unchanged.\n",
+			result.Added, result.Removed, result.Updated, result.Unchanged)
+	}
+	if result.Partial {
+		say("Extraction interrupted after %s: %d of %d question(s) finished.\n",
+			utils.TimeSince(startTime), len(result.Questions), len(sortedLinks))
+		return result
+	}
+	say("Extraction complete in %s.\n", utils.TimeSince(startTime))
+
+	return result
+}
 
-	return finalData
+// acquireWorkerSlot blocks until a concurrency slot is free. It returns
+// false, holding nothing, once ctx is done so pending workers drain without
+// issuing new requests. Pacing is left to the shared limiter in HTTPFetcher,
+// so pages served from the cache are not slowed down.
+func acquireWorkerSlot(ctx context.Context, sem chan struct{}) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case sem <- struct{}{}:
+		return true
+	}
+}
+
+// indexQuestionsByLink keys questions by their normalized discussion link,
+// the same form the discussion list pages yield.
+func indexQuestionsByLink(questions []models.QuestionData) map[string]models.QuestionData {
+	index := make(map[string]models.QuestionData, len(questions))
+	for _, question := range questions {
+		link := normalizeDiscussionViewHref(question.QuestionLink)
+		if link == "" {
+			continue
+		}
+		index[link] = question
+	}
+	return index
 }
 
 func buildSelectedExamVariantSummary(providerName, selectedExam string, links []string) string {
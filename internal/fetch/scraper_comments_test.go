@@ -1,9 +1,12 @@
 package fetch
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
+	"examtopics-downloader/internal/models"
+
 	"github.com/PuerkitoBio/goquery"
 )
 
@@ -64,3 +67,49 @@ second line</div>
 		t.Fatalf("expected second comment text, got %q", comments[1].Text)
 	}
 }
+
+func TestExtractDiscussionCommentsThreads(t *testing.T) {
+	html := `
+<div class="discussion-container">
+  <div id="comment-10" class="media comment-container">
+    <div class="media-body">
+      <div class="comment-head">
+        <h5 class="comment-username">alice</h5>
+        <span class="badge badge-primary">Highly Voted</span>
+        <span class="comment-date" title="Tue 12 Mar 2024 10:15">2 years ago</span>
+      </div>
+      <div class="comment-body"><div class="comment-content">B is right</div></div>
+      <div class="comment-control"><span class="upvote-text">upvoted <span class="upvote-count">25</span> times</span></div>
+      <div class="comment-replies">
+        <div id="comment-11" class="media comment-container">
+          <div class="media-body">
+            <div class="comment-head">
+              <h5 class="comment-username">bob</h5>
+              <span class="badge badge-primary">Most Recent</span>
+              <span class="comment-date">1 month ago</span>
+            </div>
+            <div class="comment-body">
+              <div class="comment-selected-answers">Selected Answer: <strong>D</strong></div>
+              <div class="comment-content">I think D</div>
+            </div>
+          </div>
+        </div>
+      </div>
+    </div>
+  </div>
+</div>`
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("failed parsing test html: %v", err)
+	}
+
+	got := extractDiscussionComments(doc)
+	want := []models.CommentData{
+		{ID: "10", User: "alice", Text: "B is right", Date: "2024-03-12T10:15:00Z", Upvotes: 25, HighlyVoted: true},
+		{ID: "11", User: "bob", Answer: "D", Text: "I think D", ParentID: "10", Date: "1 month ago", MostRecent: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+}
@@ -39,3 +39,21 @@ func TestExtractExhibitImageURLs(t *testing.T) {
 		}
 	}
 }
+
+func TestAnswerImagesAreSeparateFromExhibits(t *testing.T) {
+	html := `
+<p class="card-text">HOTSPOT -<br><img src="https://img.examtopics.com/az-104/image10.png"></p>
+<p class="card-text question-answer">Suggested Answer: <span class="correct-answer"><img src="https://img.examtopics.com/az-104/image11.png"></span></p>`
+
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("failed parsing test html: %v", err)
+	}
+
+	if got := extractExhibitImageURLs(doc); len(got) != 1 || got[0] != "https://img.examtopics.com/az-104/image10.png" {
+		t.Fatalf("exhibits: want only the answer area image, got %#v", got)
+	}
+	if got := extractAnswerImageURLs(doc); len(got) != 1 || got[0] != "https://img.examtopics.com/az-104/image11.png" {
+		t.Fatalf("answer images: want the suggested answer image, got %#v", got)
+	}
+}
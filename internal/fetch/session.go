@@ -0,0 +1,395 @@
+package fetch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"examtopics-downloader/internal/utils"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// loggedInSelector matches navigation links only shown to a signed-in user.
+const loggedInSelector = `a[href*="logout"], a[href*="/profile"], a[href*="/account"]`
+
+var (
+	sessionJar     *CookieJar
+	sessionExpired atomic.Bool
+)
+
+// SetCookieJar sends jar's cookies with every request of fetchers created
+// afterwards and enables session expiry checks. nil browses as a guest.
+func SetCookieJar(jar *CookieJar) {
+	sessionJar = jar
+	sessionExpired.Store(false)
+}
+
+// SessionExpired reports whether a page fetched with the session cookies
+// came back logged out during the current extraction.
+func SessionExpired() bool {
+	return sessionExpired.Load()
+}
+
+// checkSession flags the session as expired when a page that should have
+// been served to a signed-in user is a login wall or lacks the account
+// links, and drops that page from the cache. It warns once per extraction.
+func checkSession(doc *goquery.Document, kind FetchErrorKind, url string) {
+	if sessionJar == nil {
+		return
+	}
+	switch {
+	case kind == ErrorKindLoginWall:
+	case kind == "" && doc.Find(loggedInSelector).Length() == 0:
+	default:
+		return
+	}
+	// Do not serve the logged-out page from the cache once the session is
+	// renewed.
+	httpCache.remove(url)
+	if sessionExpired.CompareAndSwap(false, true) {
+		statusf("Warning: the imported session looks expired (logged-out page at %s); "+
+			"pages are fetched as a guest. Export fresh cookies and pass --cookies again.\n", url)
+	}
+}
+
+// DefaultCookieJarPath is where imported cookies are kept between runs.
+func DefaultCookieJarPath() string {
+	baseDir, err := os.UserCacheDir()
+	if err == nil && strings.TrimSpace(baseDir) != "" {
+		return filepath.Join(baseDir, "examtopics-downloader", "session_cookies.json")
+	}
+	return filepath.Join(".", ".examtopics_session_cookies.json")
+}
+
+// CookieJar is an http.CookieJar that can import browser cookie exports and
+// persists every change to a JSON file, so a session survives across runs
+// and picks up cookies the site refreshes.
+type CookieJar struct {
+	path string
+
+	mu      sync.Mutex
+	cookies map[string]storedCookie
+}
+
+type storedCookie struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain"`
+	Path     string    `json:"path"`
+	Expires  time.Time `json:"expires,omitempty"`
+	Secure   bool      `json:"secure,omitempty"`
+	HTTPOnly bool      `json:"http_only,omitempty"`
+	// HostOnly cookies are sent to Domain itself but not its subdomains.
+	HostOnly bool `json:"host_only,omitempty"`
+}
+
+func (c storedCookie) key() string {
+	return c.Domain + ";" + c.Path + ";" + c.Name
+}
+
+func (c storedCookie) expired(now time.Time) bool {
+	return !c.Expires.IsZero() && !c.Expires.After(now)
+}
+
+// matches applies the RFC 6265 domain, path and secure rules.
+func (c storedCookie) matches(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	if host != c.Domain && (c.HostOnly || !strings.HasSuffix(host, "."+c.Domain)) {
+		return false
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if path != c.Path && !strings.HasPrefix(path, strings.TrimSuffix(c.Path, "/")+"/") {
+		return false
+	}
+	return !c.Secure || u.Scheme == "https"
+}
+
+// OpenCookieJar loads the jar persisted at path; a missing file gives an
+// empty jar.
+func OpenCookieJar(path string) (*CookieJar, error) {
+	jar := &CookieJar{path: path, cookies: map[string]storedCookie{}}
+
+	payload, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return jar, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cookie jar %q: %w", path, err)
+	}
+
+	var stored []storedCookie
+	if err := json.Unmarshal(payload, &stored); err != nil {
+		return nil, fmt.Errorf("invalid cookie jar %q: %w", path, err)
+	}
+	now := time.Now()
+	for _, cookie := range stored {
+		if !cookie.expired(now) {
+			jar.cookies[cookie.key()] = cookie
+		}
+	}
+	return jar, nil
+}
+
+// Len reports how many unexpired cookies the jar holds.
+func (j *CookieJar) Len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.cookies)
+}
+
+// Import adds the cookies of a Netscape cookies.txt or JSON cookie export
+// that apply to the scraped site, replacing older values, and saves the
+// jar. It returns how many cookies were imported.
+func (j *CookieJar) Import(exportPath string) (int, error) {
+	payload, err := os.ReadFile(exportPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cookies %q: %w", exportPath, err)
+	}
+
+	var imported []storedCookie
+	trimmed := bytes.TrimSpace(payload)
+	if len(trimmed) > 0 && (trimmed[0] == '[' || trimmed[0] == '{') {
+		imported, err = parseJSONCookies(trimmed)
+	} else {
+		imported, err = parseNetscapeCookies(payload)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid cookies %q: %w", exportPath, err)
+	}
+
+	site, err := url.Parse(utils.BaseURL() + "/")
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	count := 0
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, cookie := range imported {
+		if cookie.expired(now) || !cookie.matches(site) {
+			continue
+		}
+		j.cookies[cookie.key()] = cookie
+		count++
+	}
+	if count == 0 {
+		return 0, fmt.Errorf("no unexpired cookies for %s in %q", site.Hostname(), exportPath)
+	}
+	// Pages cached with the previous cookies may be logged out.
+	httpCache.removeSessionEntries()
+	return count, j.saveLocked()
+}
+
+// SetCookies implements http.CookieJar.
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	now := time.Now()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, c := range cookies {
+		stored := storedCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   strings.TrimPrefix(strings.ToLower(c.Domain), "."),
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HTTPOnly: c.HttpOnly,
+		}
+		if stored.Domain == "" {
+			stored.Domain = strings.ToLower(u.Hostname())
+			stored.HostOnly = true
+		}
+		if stored.Path == "" || !strings.HasPrefix(stored.Path, "/") {
+			stored.Path = "/"
+		}
+		switch {
+		case c.MaxAge < 0:
+			stored.Expires = now
+		case c.MaxAge > 0:
+			stored.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		case !c.Expires.IsZero():
+			stored.Expires = c.Expires
+		}
+
+		if stored.expired(now) {
+			delete(j.cookies, stored.key())
+		} else {
+			j.cookies[stored.key()] = stored
+		}
+	}
+	if err := j.saveLocked(); err != nil {
+		debugf("failed to save cookie jar: %v", err)
+	}
+}
+
+// Cookies implements http.CookieJar.
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	now := time.Now()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var matched []storedCookie
+	for _, cookie := range j.cookies {
+		if !cookie.expired(now) && cookie.matches(u) {
+			matched = append(matched, cookie)
+		}
+	}
+	// More specific paths first, as browsers send them.
+	sort.Slice(matched, func(a, b int) bool {
+		if len(matched[a].Path) != len(matched[b].Path) {
+			return len(matched[a].Path) > len(matched[b].Path)
+		}
+		return matched[a].Name < matched[b].Name
+	})
+
+	cookies := make([]*http.Cookie, 0, len(matched))
+	for _, cookie := range matched {
+		cookies = append(cookies, &http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	return cookies
+}
+
+func (j *CookieJar) saveLocked() error {
+	if j.path == "" {
+		return nil
+	}
+
+	stored := make([]storedCookie, 0, len(j.cookies))
+	for _, cookie := range j.cookies {
+		stored = append(stored, cookie)
+	}
+	sort.Slice(stored, func(a, b int) bool { return stored[a].key() < stored[b].key() })
+
+	payload, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create cookie jar dir: %w", err)
+	}
+	// Session cookies are credentials: keep them private to the user.
+	if err := os.WriteFile(j.path, payload, 0o600); err != nil {
+		return fmt.Errorf("failed to write cookie jar %q: %w", j.path, err)
+	}
+	return nil
+}
+
+// parseNetscapeCookies reads the tab-separated cookies.txt format written by
+// curl, wget and browser extensions.
+func parseNetscapeCookies(payload []byte) ([]storedCookie, error) {
+	var cookies []storedCookie
+	scanner := bufio.NewScanner(bytes.NewReader(payload))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := false
+		if rest, ok := strings.CutPrefix(line, "#HttpOnly_"); ok {
+			line, httpOnly = rest, true
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("line %d: want 7 tab-separated fields, got %d", lineNo, len(fields))
+		}
+		expiry, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid expiry %q", lineNo, fields[4])
+		}
+
+		cookie := storedCookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Domain:   strings.TrimPrefix(strings.ToLower(fields[0]), "."),
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HTTPOnly: httpOnly,
+			HostOnly: !strings.EqualFold(fields[1], "TRUE"),
+		}
+		if expiry > 0 {
+			cookie.Expires = time.Unix(expiry, 0)
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies, scanner.Err()
+}
+
+// jsonCookie covers the browser extension export format (expirationDate) and
+// Playwright/Puppeteer storage state (expires, -1 for session cookies).
+type jsonCookie struct {
+	Name           string   `json:"name"`
+	Value          string   `json:"value"`
+	Domain         string   `json:"domain"`
+	Path           string   `json:"path"`
+	ExpirationDate *float64 `json:"expirationDate"`
+	Expires        *float64 `json:"expires"`
+	Secure         bool     `json:"secure"`
+	HTTPOnly       bool     `json:"httpOnly"`
+	HostOnly       *bool    `json:"hostOnly"`
+}
+
+func parseJSONCookies(payload []byte) ([]storedCookie, error) {
+	var list []jsonCookie
+	if payload[0] == '{' {
+		var state struct {
+			Cookies []jsonCookie `json:"cookies"`
+		}
+		if err := json.Unmarshal(payload, &state); err != nil {
+			return nil, err
+		}
+		list = state.Cookies
+	} else if err := json.Unmarshal(payload, &list); err != nil {
+		return nil, err
+	}
+
+	cookies := make([]storedCookie, 0, len(list))
+	for i, c := range list {
+		if c.Name == "" || c.Domain == "" {
+			return nil, fmt.Errorf("cookie %d: name and domain are required", i+1)
+		}
+		cookie := storedCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   strings.TrimPrefix(strings.ToLower(c.Domain), "."),
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HTTPOnly: c.HTTPOnly,
+		}
+		if cookie.Path == "" {
+			cookie.Path = "/"
+		}
+		if c.HostOnly != nil {
+			cookie.HostOnly = *c.HostOnly
+		} else {
+			cookie.HostOnly = !strings.HasPrefix(c.Domain, ".")
+		}
+		expiry := c.ExpirationDate
+		if expiry == nil {
+			expiry = c.Expires
+		}
+		if expiry != nil && *expiry > 0 {
+			sec, frac := math.Modf(*expiry)
+			cookie.Expires = time.Unix(int64(sec), int64(frac*1e9))
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies, nil
+}
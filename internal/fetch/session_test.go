@@ -0,0 +1,255 @@
+package fetch
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"examtopics-downloader/internal/utils"
+)
+
+func writeCookieExport(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("writing cookies: %v", err)
+	}
+	return path
+}
+
+func cookieHeader(jar *CookieJar, rawURL string) string {
+	u, _ := url.Parse(rawURL)
+	req := &http.Request{Header: http.Header{}}
+	for _, cookie := range jar.Cookies(u) {
+		req.AddCookie(cookie)
+	}
+	return req.Header.Get("Cookie")
+}
+
+func TestCookieJarImportsNetscapeExport(t *testing.T) {
+	useTempHTTPCache(t, CacheDefault)
+	future := time.Now().Add(time.Hour).Unix()
+	export := writeCookieExport(t, "cookies.txt", "# Netscape HTTP Cookie File\n"+
+		".examtopics.com\tTRUE\t/\tTRUE\t"+strconv.FormatInt(future, 10)+"\tsessionid\tabc\n"+
+		"#HttpOnly_www.examtopics.com\tFALSE\t/\tFALSE\t0\tcsrftoken\txyz\n"+
+		".examtopics.com\tTRUE\t/\tFALSE\t1\told\tgone\n"+
+		".example.org\tTRUE\t/\tFALSE\t0\tother\tsite\n")
+
+	jarPath := filepath.Join(t.TempDir(), "jar.json")
+	jar, err := OpenCookieJar(jarPath)
+	if err != nil {
+		t.Fatalf("OpenCookieJar: %v", err)
+	}
+	imported, err := jar.Import(export)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if imported != 2 {
+		t.Fatalf("expired and foreign cookies should be skipped: want 2, got %d", imported)
+	}
+
+	if got := cookieHeader(jar, "https://www.examtopics.com/exams/"); got != "csrftoken=xyz; sessionid=abc" {
+		t.Fatalf("cookies for https: got %q", got)
+	}
+	if got := cookieHeader(jar, "http://www.examtopics.com/exams/"); got != "csrftoken=xyz" {
+		t.Fatalf("secure cookies must not be sent over http: got %q", got)
+	}
+
+	reopened, err := OpenCookieJar(jarPath)
+	if err != nil {
+		t.Fatalf("reopening jar: %v", err)
+	}
+	if reopened.Len() != 2 {
+		t.Fatalf("persisted jar: want 2 cookies, got %d", reopened.Len())
+	}
+}
+
+func TestCookieJarImportsJSONExports(t *testing.T) {
+	useTempHTTPCache(t, CacheDefault)
+	exports := map[string]string{
+		"extension.json": `[{"name":"sessionid","value":"abc","domain":".examtopics.com","path":"/","expirationDate":4102444800.5,"hostOnly":false,"secure":true}]`,
+		"storage.json":   `{"cookies":[{"name":"sessionid","value":"abc","domain":".examtopics.com","path":"/","expires":-1,"secure":true}]}`,
+	}
+	for name, content := range exports {
+		jar, _ := OpenCookieJar("")
+		if _, err := jar.Import(writeCookieExport(t, name, content)); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := cookieHeader(jar, "https://www.examtopics.com/"); got != "sessionid=abc" {
+			t.Fatalf("%s: want %q, got %q", name, "sessionid=abc", got)
+		}
+	}
+
+	jar, _ := OpenCookieJar("")
+	if _, err := jar.Import(writeCookieExport(t, "none.json", `[{"name":"a","value":"b","domain":"example.org"}]`)); err == nil {
+		t.Fatal("an export without cookies for the site should be rejected")
+	}
+}
+
+func TestCookieJarFollowsSetCookie(t *testing.T) {
+	jar, _ := OpenCookieJar("")
+	site, _ := url.Parse("https://www.examtopics.com/discussions/")
+
+	jar.SetCookies(site, []*http.Cookie{{Name: "sessionid", Value: "new"}})
+	if got := cookieHeader(jar, "https://www.examtopics.com/exams/"); got != "sessionid=new" {
+		t.Fatalf("refreshed cookie: got %q", got)
+	}
+	if got := cookieHeader(jar, "https://sub.www.examtopics.com/"); got != "" {
+		t.Fatalf("host-only cookies must not reach subdomains: got %q", got)
+	}
+
+	jar.SetCookies(site, []*http.Cookie{{Name: "sessionid", MaxAge: -1}})
+	if jar.Len() != 0 {
+		t.Fatalf("a negative Max-Age should delete the cookie, %d left", jar.Len())
+	}
+}
+
+func TestCheckSessionDetectsLoggedOutPages(t *testing.T) {
+	useTempHTTPCache(t, CacheDefault)
+	base := "https://www.examtopics.com/discussions/cisco/"
+	useTuning(t, fastTuning())
+	previousOutput := statusOutput
+	SetOutput(io.Discard)
+	t.Cleanup(func() { statusOutput = previousOutput })
+	useFetcher(t, stubFetcher{
+		base + "1": `<nav><a href="/logout/">Log out</a></nav><p>Discussion list for signed-in users</p>`,
+		base + "2": `<nav><a href="/login/">Log in</a></nav><p>Discussion list shown to guests</p>`,
+	})
+
+	jar, _ := OpenCookieJar("")
+	SetCookieJar(jar)
+	t.Cleanup(func() { SetCookieJar(nil) })
+
+	if _, err := ParseHTML(context.Background(), base+"1"); err != nil || SessionExpired() {
+		t.Fatalf("signed-in page: err %v, expired %v", err, SessionExpired())
+	}
+	if _, err := ParseHTML(context.Background(), base+"2"); err != nil || !SessionExpired() {
+		t.Fatalf("logged-out page should flag the session: err %v, expired %v", err, SessionExpired())
+	}
+}
+
+func TestSessionDoesNotReuseGuestCache(t *testing.T) {
+	useTempHTTPCache(t, CacheDefault)
+	useTuning(t, fastTuning())
+	previousOutput := statusOutput
+	SetOutput(io.Discard)
+	t.Cleanup(func() { statusOutput = previousOutput })
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if _, err := r.Cookie("sessionid"); err == nil {
+			w.Write([]byte(`<nav><a href="/logout/">Log out</a></nav><p class="card-text">Question for signed-in users</p>`))
+			return
+		}
+		w.Write([]byte(`<nav><a href="/login/">Log in</a></nav><p class="card-text">Question shown to guests</p>`))
+	}))
+	defer server.Close()
+	page := server.URL + "/discussions/cisco/view/1-exam-200-301-topic-1-question-1-discussion/"
+
+	useFetcher(t, NewHTTPFetcher(server.Client()))
+	if _, err := ParseHTML(context.Background(), page); err != nil {
+		t.Fatalf("guest fetch: %v", err)
+	}
+
+	jar, _ := OpenCookieJar("")
+	serverURL, _ := url.Parse(server.URL)
+	jar.SetCookies(serverURL, []*http.Cookie{{Name: "sessionid", Value: "abc"}})
+	SetCookieJar(jar)
+	t.Cleanup(func() { SetCookieJar(nil) })
+	client := server.Client()
+	client.Jar = jar
+	useFetcher(t, NewHTTPFetcher(client))
+
+	doc, err := ParseHTML(context.Background(), page)
+	if err != nil {
+		t.Fatalf("session fetch: %v", err)
+	}
+	if got := doc.Find(".card-text").Text(); got != "Question for signed-in users" || requests.Load() != 2 {
+		t.Fatalf("want the signed-in page from the network, got %q after %d requests", got, requests.Load())
+	}
+	if SessionExpired() {
+		t.Fatal("a valid session should not be flagged")
+	}
+
+	// A cached page is not evidence about the session, even if logged out.
+	stale := server.URL + "/discussions/cisco/view/2-exam-200-301-topic-1-question-2-discussion/"
+	httpCache.store(&cachedResponse{URL: stale, FetchedAt: time.Now(), Body: []byte(`<a href="/login/">Log in</a><p class="card-text">Cached question</p>`)})
+	if _, err := ParseHTML(context.Background(), stale); err != nil || requests.Load() != 2 {
+		t.Fatalf("want the cached page without a request: err %v, %d requests", err, requests.Load())
+	}
+	if SessionExpired() {
+		t.Fatal("a cache hit should not flag the session as expired")
+	}
+}
+
+func TestCookieJarImportsCookiesOfTheBaseURL(t *testing.T) {
+	useTempHTTPCache(t, CacheDefault)
+	if err := utils.SetBaseURL("https://mirror.example.org"); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+	t.Cleanup(func() { utils.SetBaseURL("") })
+
+	export := writeCookieExport(t, "cookies.txt", "# Netscape HTTP Cookie File\n"+
+		"mirror.example.org\tFALSE\t/\tTRUE\t0\tsessionid\tmirror\n"+
+		".examtopics.com\tTRUE\t/\tTRUE\t0\tsessionid\tupstream\n")
+
+	jar, _ := OpenCookieJar("")
+	imported, err := jar.Import(export)
+	if err != nil || imported != 1 {
+		t.Fatalf("want only the mirror cookie imported, got %d (err %v)", imported, err)
+	}
+	if got := cookieHeader(jar, "https://mirror.example.org/exams/"); got != "sessionid=mirror" {
+		t.Fatalf("cookies for the mirror: got %q", got)
+	}
+}
+
+func TestLoggedOutSessionPagesLeaveTheCache(t *testing.T) {
+	useTempHTTPCache(t, CacheDefault)
+	useTuning(t, fastTuning())
+	previousOutput := statusOutput
+	SetOutput(io.Discard)
+	t.Cleanup(func() { statusOutput = previousOutput })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<nav><a href="/login/">Log in</a></nav><p class="card-text">Question shown to guests</p>`))
+	}))
+	defer server.Close()
+	page := server.URL + "/discussions/cisco/view/1-exam-200-301-topic-1-question-1-discussion/"
+
+	jar, _ := OpenCookieJar("")
+	SetCookieJar(jar)
+	t.Cleanup(func() { SetCookieJar(nil) })
+	useFetcher(t, NewHTTPFetcher(server.Client()))
+
+	if _, err := ParseHTML(context.Background(), page); err != nil || !SessionExpired() {
+		t.Fatalf("logged-out page should flag the session: err %v, expired %v", err, SessionExpired())
+	}
+	if _, ok := httpCache.load(page); ok {
+		t.Fatal("the logged-out page should not stay in the session cache")
+	}
+
+	// Importing fresh cookies drops whatever the old session cached.
+	cached := server.URL + "/discussions/cisco/view/2-exam-200-301-topic-1-question-2-discussion/"
+	httpCache.store(&cachedResponse{URL: cached, FetchedAt: time.Now(), Body: []byte("old session page")})
+	if err := utils.SetBaseURL(server.URL); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+	t.Cleanup(func() { utils.SetBaseURL("") })
+	host, _ := url.Parse(server.URL)
+	export := writeCookieExport(t, "cookies.txt", host.Hostname()+"\tFALSE\t/\tFALSE\t0\tsessionid\tfresh\n")
+	if _, err := jar.Import(export); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if _, ok := httpCache.load(cached); ok {
+		t.Fatal("importing cookies should drop pages cached with the old session")
+	}
+}
@@ -0,0 +1,36 @@
+package fetch
+
+import (
+	"time"
+
+	"examtopics-downloader/internal/constants"
+)
+
+// Tuning controls request pacing and retries.
+type Tuning struct {
+	RequestsPerSecond     float64
+	MaxConcurrentRequests int
+	MaxRetries            int
+	InitialBackoff        time.Duration
+	BackoffFactor         float64
+}
+
+// DefaultTuning returns the built-in values from the constants package.
+func DefaultTuning() Tuning {
+	return Tuning{
+		RequestsPerSecond:     constants.RequestsPerSecond,
+		MaxConcurrentRequests: constants.MaxConcurrentRequests,
+		MaxRetries:            constants.MaxRetries,
+		InitialBackoff:        constants.InitalBackoff,
+		BackoffFactor:         constants.BackoffFactor,
+	}
+}
+
+var tuning = DefaultTuning()
+
+// SetTuning replaces the pacing used by subsequent requests. Values are
+// expected to be validated by the caller.
+func SetTuning(t Tuning) {
+	tuning = t
+	limiter.reset(t.RequestsPerSecond)
+}
@@ -0,0 +1,89 @@
+package fetch
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"examtopics-downloader/internal/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// voteTallyEntry is one answer in the JSON tally ExamTopics embeds next to
+// the "Community vote distribution" bar.
+type voteTallyEntry struct {
+	Answers string `json:"voted_answers"`
+	Count   int    `json:"vote_count"`
+}
+
+var voteBarPattern = regexp.MustCompile(`(?i)\b([A-F]{1,6})\s*\((\d+(?:\.\d+)?)\s*%\)`)
+
+// extractVoteDistribution reads the community vote distribution, preferring
+// the embedded tally, which has exact counts, over the text of the bar
+// ("B (72%) C (28%)"). It returns nil when nobody has voted.
+func extractVoteDistribution(doc *goquery.Document) []models.VoteShare {
+	if votes := votesFromTally(doc.Find(".voted-answers-tally script").First().Text()); len(votes) > 0 {
+		return votes
+	}
+
+	var votes []models.VoteShare
+	seen := map[string]struct{}{}
+	doc.Find(".vote-distribution-bar, .vote-bar").Each(func(i int, s *goquery.Selection) {
+		for _, m := range voteBarPattern.FindAllStringSubmatch(s.Text(), -1) {
+			answer := strings.ToUpper(m[1])
+			if _, dup := seen[answer]; dup {
+				continue
+			}
+			seen[answer] = struct{}{}
+			percent, _ := strconv.ParseFloat(m[2], 64)
+			votes = append(votes, models.VoteShare{Answer: answer, Percent: percent})
+		}
+	})
+	sortVotes(votes)
+	return votes
+}
+
+func votesFromTally(raw string) []models.VoteShare {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	var tally []voteTallyEntry
+	if err := json.Unmarshal([]byte(raw), &tally); err != nil {
+		debugf("failed to parse vote tally: %v", err)
+		return nil
+	}
+
+	total := 0
+	for _, entry := range tally {
+		total += entry.Count
+	}
+	if total == 0 {
+		return nil
+	}
+
+	votes := make([]models.VoteShare, 0, len(tally))
+	for _, entry := range tally {
+		answer := strings.ToUpper(strings.TrimSpace(entry.Answers))
+		if answer == "" || entry.Count <= 0 {
+			continue
+		}
+		votes = append(votes, models.VoteShare{
+			Answer:  answer,
+			Count:   entry.Count,
+			Percent: math.Round(float64(entry.Count)*1000/float64(total)) / 10,
+		})
+	}
+	sortVotes(votes)
+	return votes
+}
+
+func sortVotes(votes []models.VoteShare) {
+	sort.SliceStable(votes, func(i, j int) bool {
+		return votes[i].Percent > votes[j].Percent
+	})
+}
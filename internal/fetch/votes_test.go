@@ -0,0 +1,44 @@
+package fetch
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"examtopics-downloader/internal/models"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestExtractVoteDistribution(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want []models.VoteShare
+	}{
+		{
+			name: "embedded tally",
+			html: `<div class="voted-answers-tally"><script type="application/json">[{"voted_answers": "C", "vote_count": 3, "is_most_voted": false}, {"voted_answers": "BD", "vote_count": 9, "is_most_voted": true}]</script></div>`,
+			want: []models.VoteShare{{Answer: "BD", Count: 9, Percent: 75}, {Answer: "C", Count: 3, Percent: 25}},
+		},
+		{
+			name: "bar text",
+			html: `<div class="vote-distribution-bar"><div class="vote-bar">B (72%)</div><div class="vote-bar">a (28%)</div></div>`,
+			want: []models.VoteShare{{Answer: "B", Percent: 72}, {Answer: "A", Percent: 28}},
+		},
+		{
+			name: "no votes",
+			html: `<div class="discussion-container"></div>`,
+		},
+	}
+
+	for _, tc := range tests {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(tc.html))
+		if err != nil {
+			t.Fatalf("failed parsing test html: %v", err)
+		}
+		if got := extractVoteDistribution(doc); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%s: want %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// DatasetSchemaVersion is bumped whenever the dataset layout changes in a
+// way older readers cannot handle.
+const DatasetSchemaVersion = 1
+
+// Dataset is the canonical on-disk form of a scrape. It keeps every field
+// extracted from the discussion pages so output can be re-rendered, diffed
+// or post-processed without fetching the pages again.
+type Dataset struct {
+	SchemaVersion int       `json:"schema_version"`
+	Provider      string    `json:"provider"`
+	ExamSlug      string    `json:"exam_slug"`
+	ScrapedAt     time.Time `json:"scraped_at"`
+	// Partial is set when the scrape was interrupted before it finished.
+	Partial   bool           `json:"partial,omitempty"`
+	Questions []QuestionData `json:"questions"`
+}
+
+func NewDataset(provider, examSlug string, questions []QuestionData) *Dataset {
+	return &Dataset{
+		SchemaVersion: DatasetSchemaVersion,
+		Provider:      provider,
+		ExamSlug:      examSlug,
+		ScrapedAt:     time.Now().UTC(),
+		Questions:     questions,
+	}
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// FetchFailure is a page that could not be fetched or parsed during a scrape.
+type FetchFailure struct {
+	URL string `json:"url"`
+	// Page is "question" or "discussion_list".
+	Page   string `json:"page"`
+	Kind   string `json:"kind"`
+	Status int    `json:"status,omitempty"`
+	Reason string `json:"reason"`
+	// KeptPrevious is set when an incremental refresh kept the stored copy.
+	KeptPrevious bool `json:"kept_previous,omitempty"`
+}
+
+// FailureReport lists every failed page of a scrape.
+type FailureReport struct {
+	Provider    string         `json:"provider"`
+	ExamSlug    string         `json:"exam_slug"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	Failures    []FetchFailure `json:"failures"`
+}
+
+func NewFailureReport(provider, examSlug string, failures []FetchFailure) *FailureReport {
+	return &FailureReport{
+		Provider:    provider,
+		ExamSlug:    examSlug,
+		GeneratedAt: time.Now().UTC(),
+		Failures:    failures,
+	}
+}
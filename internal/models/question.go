@@ -1,19 +1,63 @@
 package models
 
 type CommentData struct {
-	User   string
-	Answer string
-	Text   string
+	ID     string `json:"id,omitempty"`
+	User   string `json:"user"`
+	Answer string `json:"answer"`
+	Text   string `json:"text"`
+	// ParentID is the ID of the comment this one replies to.
+	ParentID string `json:"parent_id,omitempty"`
+	// Date is RFC 3339 when the page gave an absolute date, else as shown.
+	Date        string `json:"date,omitempty"`
+	Upvotes     int    `json:"upvotes,omitempty"`
+	HighlyVoted bool   `json:"highly_voted,omitempty"`
+	MostRecent  bool   `json:"most_recent,omitempty"`
 }
 
 type QuestionData struct {
-	Title        string
-	Header       string
-	Content      string
-	ExhibitURLs  []string
-	Questions    []string
-	Answer       string
-	Timestamp    string
-	QuestionLink string
-	Comments     []CommentData
+	Title   string `json:"title"`
+	Header  string `json:"header"`
+	Content string `json:"content"`
+	// ContentHTML is the question body as sanitized HTML, keeping code
+	// blocks, tables, lists and line breaks that Content flattens.
+	ContentHTML  string        `json:"content_html,omitempty"`
+	ExhibitURLs  []string      `json:"exhibit_urls"`
+	Questions    []string      `json:"questions"`
+	Answer       string        `json:"answer"`
+	Timestamp    string        `json:"timestamp"`
+	QuestionLink string        `json:"question_link"`
+	Comments     []CommentData `json:"comments"`
+	// Topic and QuestionNumber place the question within its exam, e.g.
+	// Topic 2 Question 5. They are 0 when the page does not show them.
+	Topic          int `json:"topic,omitempty"`
+	QuestionNumber int `json:"question_number,omitempty"`
+	// ExamSlug is the exam named by the discussion link, before version
+	// variants are collapsed.
+	ExamSlug string `json:"exam_slug,omitempty"`
+	// Type is one of the QuestionType constants.
+	Type string `json:"type,omitempty"`
+	// AnswerImageURLs are the suggested answer images of questions answered
+	// in an answer area rather than by letter.
+	AnswerImageURLs []string `json:"answer_image_urls,omitempty"`
+	// Votes is the community vote distribution, most voted first.
+	Votes []VoteShare `json:"votes,omitempty"`
 }
+
+// VoteShare is the share of community votes for one answer, e.g. "B" with
+// 72%. Multiple-answer questions are voted on as a whole, e.g. "BD".
+type VoteShare struct {
+	Answer  string  `json:"answer"`
+	Count   int     `json:"count,omitempty"`
+	Percent float64 `json:"percent"`
+}
+
+// Question types recorded in QuestionData.Type. Only multiple choice
+// questions have lettered options; the others are answered in an answer
+// area shown as an image.
+const (
+	QuestionTypeMultipleChoice = "multiple_choice"
+	QuestionTypeHotspot        = "hotspot"
+	QuestionTypeDragAndDrop    = "drag_and_drop"
+	QuestionTypeYesNo          = "yes_no"
+	QuestionTypeOther          = "other"
+)
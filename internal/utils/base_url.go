@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// DefaultBaseURL is the ExamTopics origin used unless SetBaseURL overrides it.
+const DefaultBaseURL = "https://www.examtopics.com"
+
+var baseURL = DefaultBaseURL
+
+// SetBaseURL points all URL building at another origin, such as a mirror or
+// a local fixture server. Only scheme, host and port are allowed; an empty
+// value restores DefaultBaseURL.
+func SetBaseURL(raw string) error {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		baseURL = DefaultBaseURL
+		return nil
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid base URL %q: %w", raw, err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("invalid base URL %q: scheme must be http or https", raw)
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("invalid base URL %q: missing host", raw)
+	}
+	if strings.Trim(parsed.Path, "/") != "" || parsed.RawQuery != "" || parsed.Fragment != "" {
+		return fmt.Errorf("invalid base URL %q: must be an origin without path or query", raw)
+	}
+
+	baseURL = strings.ToLower(parsed.Scheme + "://" + parsed.Host)
+	return nil
+}
+
+// BaseURL returns the configured origin without a trailing slash.
+func BaseURL() string {
+	return baseURL
+}
+
+// BaseHost returns the host (and port, if any) of the configured origin.
+func BaseHost() string {
+	return strings.TrimPrefix(strings.TrimPrefix(baseURL, "https://"), "http://")
+}
@@ -0,0 +1,34 @@
+package utils
+
+import "testing"
+
+func TestSetBaseURLNormalizesOrigin(t *testing.T) {
+	t.Cleanup(func() { SetBaseURL("") })
+
+	if err := SetBaseURL("HTTP://127.0.0.1:8080/"); err != nil {
+		t.Fatalf("SetBaseURL: %v", err)
+	}
+	if got := AddToBaseUrl("/exams/"); got != "http://127.0.0.1:8080/exams/" {
+		t.Fatalf("AddToBaseUrl: want %q, got %q", "http://127.0.0.1:8080/exams/", got)
+	}
+	if got := BaseHost(); got != "127.0.0.1:8080" {
+		t.Fatalf("BaseHost: want %q, got %q", "127.0.0.1:8080", got)
+	}
+
+	if err := SetBaseURL(""); err != nil || BaseURL() != DefaultBaseURL {
+		t.Fatalf("empty base URL should restore the default, got %q (err %v)", BaseURL(), err)
+	}
+}
+
+func TestSetBaseURLRejectsInvalidValues(t *testing.T) {
+	t.Cleanup(func() { SetBaseURL("") })
+
+	for _, raw := range []string{"www.examtopics.com", "ftp://mirror.local", "https://", "https://mirror.local/examtopics"} {
+		if err := SetBaseURL(raw); err == nil {
+			t.Fatalf("SetBaseURL(%q): expected an error", raw)
+		}
+	}
+	if BaseURL() != DefaultBaseURL {
+		t.Fatalf("rejected values must not change the base URL, got %q", BaseURL())
+	}
+}
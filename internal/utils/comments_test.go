@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"encoding/json"
+	"html"
+	"reflect"
+	"testing"
+
+	"examtopics-downloader/internal/models"
+)
+
+func TestCommentsJSONKeepsThreadsAndBadges(t *testing.T) {
+	raw := []models.CommentData{
+		{ID: "10", User: "alice", Answer: "b", Text: " B is right ", Date: "2024-03-12T10:15:00Z", Upvotes: 25, HighlyVoted: true},
+		{ID: "11", ParentID: "10", Text: "I think D", MostRecent: true},
+	}
+
+	var got []templateComment
+	if err := json.Unmarshal([]byte(html.UnescapeString(buildCommentsJSON(raw, true))), &got); err != nil {
+		t.Fatalf("comments payload is not JSON: %v", err)
+	}
+	want := []templateComment{
+		{ID: "10", User: "alice", Answer: "B", Text: "B is right", Date: "2024-03-12T10:15:00Z", Upvotes: 25, HighlyVoted: true},
+		{ID: "11", ParentID: "10", User: "Anonymous", Text: "I think D", MostRecent: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+
+	if got := buildCommentsJSON(raw, false); got != "[]" {
+		t.Fatalf("comments should be left out when disabled, got %q", got)
+	}
+}
@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"examtopics-downloader/internal/models"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// WriteDataset saves the dataset as indented JSON next to outputPath, using
+// the same base name with a .json extension.
+func WriteDataset(dataset *models.Dataset, outputPath string) (string, error) {
+	payload, err := json.MarshalIndent(dataset, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode dataset: %w", err)
+	}
+
+	datasetOutput := GetDatasetOutputPath(outputPath)
+	if err := os.WriteFile(datasetOutput, append(payload, '\n'), 0644); err != nil {
+		return "", fmt.Errorf("failed to write dataset file: %w", err)
+	}
+
+	return datasetOutput, nil
+}
+
+// LoadDataset reads a dataset written by WriteDataset. Bare JSON arrays of
+// questions are accepted too and come back with schema version 0.
+func LoadDataset(path string) (*models.Dataset, error) {
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dataset %q: %w", path, err)
+	}
+
+	trimmed := bytes.TrimSpace(payload)
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		var dataList []models.QuestionData
+		if err := json.Unmarshal(trimmed, &dataList); err != nil {
+			return nil, fmt.Errorf("failed to parse dataset %q: %w", path, err)
+		}
+		return &models.Dataset{Questions: dataList}, nil
+	}
+
+	var dataset models.Dataset
+	if err := json.Unmarshal(trimmed, &dataset); err != nil {
+		return nil, fmt.Errorf("failed to parse dataset %q: %w", path, err)
+	}
+	if dataset.SchemaVersion > models.DatasetSchemaVersion {
+		return nil, fmt.Errorf("dataset %q uses schema version %d; this build supports up to %d",
+			path, dataset.SchemaVersion, models.DatasetSchemaVersion)
+	}
+
+	return &dataset, nil
+}
+
+func GetDatasetOutputPath(outputPath string) string {
+	cleanPath := strings.TrimSpace(outputPath)
+	if cleanPath == "" {
+		cleanPath = "examtopics_output"
+	}
+
+	base := strings.TrimSuffix(cleanPath, filepath.Ext(cleanPath))
+	if base == "" {
+		base = cleanPath
+	}
+
+	return base + ".json"
+}
+
+// WriteFailureReport saves the report as <base>.failures.json next to
+// outputPath. A report without failures removes the file left by an earlier
+// run instead and returns an empty path.
+func WriteFailureReport(report *models.FailureReport, outputPath string) (string, error) {
+	reportPath := GetFailureReportPath(outputPath)
+	if len(report.Failures) == 0 {
+		if err := os.Remove(reportPath); err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("failed to remove old failure report: %w", err)
+		}
+		return "", nil
+	}
+
+	payload, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode failure report: %w", err)
+	}
+	if err := os.WriteFile(reportPath, append(payload, '\n'), 0644); err != nil {
+		return "", fmt.Errorf("failed to write failure report: %w", err)
+	}
+
+	return reportPath, nil
+}
+
+func GetFailureReportPath(outputPath string) string {
+	return strings.TrimSuffix(GetDatasetOutputPath(outputPath), ".json") + ".failures.json"
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"examtopics-downloader/internal/models"
+)
+
+func TestWriteDatasetRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	questions := []models.QuestionData{
+		{
+			Title:        "Exam 200-301 topic 1 question 3 discussion",
+			Content:      "Which route is used?",
+			ExhibitURLs:  []string{"https://img.examtopics.com/200-301/image1.png"},
+			Questions:    []string{"A. static", "B. connected"},
+			Answer:       "B",
+			QuestionLink: "https://www.examtopics.com/discussions/cisco/view/1-exam-200-301-topic-1-question-3-discussion/",
+			Comments:     []models.CommentData{{User: "alice", Answer: "B", Text: "connected"}},
+		},
+	}
+
+	written, err := WriteDataset(models.NewDataset("cisco", "200-301", questions), filepath.Join(dir, "cisco_200-301.html"))
+	if err != nil {
+		t.Fatalf("WriteDataset failed: %v", err)
+	}
+	if filepath.Base(written) != "cisco_200-301.json" {
+		t.Fatalf("unexpected dataset path: %q", written)
+	}
+
+	loaded, err := LoadDataset(written)
+	if err != nil {
+		t.Fatalf("LoadDataset failed: %v", err)
+	}
+	if loaded.SchemaVersion != models.DatasetSchemaVersion {
+		t.Fatalf("unexpected schema version: %d", loaded.SchemaVersion)
+	}
+	if loaded.Provider != "cisco" || loaded.ExamSlug != "200-301" {
+		t.Fatalf("unexpected provider/exam: %q/%q", loaded.Provider, loaded.ExamSlug)
+	}
+	if loaded.ScrapedAt.IsZero() {
+		t.Fatal("expected scrape timestamp to be set")
+	}
+	if !reflect.DeepEqual(loaded.Questions, questions) {
+		t.Fatalf("questions did not round-trip:\nwant %#v\ngot  %#v", questions, loaded.Questions)
+	}
+}
+
+func TestLoadDatasetAcceptsBareArray(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.json")
+	if err := os.WriteFile(path, []byte(`[{"title":"t","answer":"A"}]`), 0644); err != nil {
+		t.Fatalf("failed writing fixture: %v", err)
+	}
+
+	loaded, err := LoadDataset(path)
+	if err != nil {
+		t.Fatalf("LoadDataset failed: %v", err)
+	}
+	if loaded.SchemaVersion != 0 || len(loaded.Questions) != 1 || loaded.Questions[0].Answer != "A" {
+		t.Fatalf("unexpected legacy dataset: %#v", loaded)
+	}
+}
+
+func TestLoadDatasetRejectsNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "future.json")
+	if err := os.WriteFile(path, []byte(`{"schema_version": 999, "questions": []}`), 0644); err != nil {
+		t.Fatalf("failed writing fixture: %v", err)
+	}
+
+	_, err := LoadDataset(path)
+	if err == nil || !strings.Contains(err.Error(), "schema version 999") {
+		t.Fatalf("expected schema version error, got %v", err)
+	}
+}
+
+func TestWriteFailureReportRemovesStaleReport(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "cisco_200-301.html")
+	failures := []models.FetchFailure{{URL: "https://www.examtopics.com/x", Page: "question", Kind: "http_status", Status: 404, Reason: "unexpected status 404 Not Found"}}
+
+	reportPath, err := WriteFailureReport(models.NewFailureReport("cisco", "200-301", failures), outputPath)
+	if err != nil {
+		t.Fatalf("WriteFailureReport: %v", err)
+	}
+	if want := filepath.Join(filepath.Dir(outputPath), "cisco_200-301.failures.json"); reportPath != want {
+		t.Fatalf("report path: want %q, got %q", want, reportPath)
+	}
+
+	reportPath, err = WriteFailureReport(models.NewFailureReport("cisco", "200-301", nil), outputPath)
+	if err != nil || reportPath != "" {
+		t.Fatalf("empty report should write nothing, got %q (err %v)", reportPath, err)
+	}
+	if _, err := os.Stat(GetFailureReportPath(outputPath)); !os.IsNotExist(err) {
+		t.Fatalf("expected the stale report to be removed, got %v", err)
+	}
+}
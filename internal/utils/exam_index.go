@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+)
+
+// ExamIndexEntry is one exam page listed by WriteExamIndex.
+type ExamIndexEntry struct {
+	Exam string
+	// File is the exam's HTML page, relative to the index.
+	File      string
+	Questions int
+}
+
+var examIndexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1.0">
+<title>{{.Company}} Exams{{if .Partial}} (partial){{end}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; margin: 0; background: #f5f7fa; color: #1f2933; }
+main { max-width: 760px; margin: 40px auto; padding: 0 20px; }
+h1 { font-size: 1.6rem; margin-bottom: 4px; }
+p.summary { color: #52606d; margin-top: 0; }
+p.partial { background: #fff4e5; border: 1px solid #f0b429; padding: 10px 14px; border-radius: 6px; }
+table { width: 100%; border-collapse: collapse; background: #fff; border-radius: 8px; overflow: hidden; box-shadow: 0 1px 3px rgba(0,0,0,.08); }
+th, td { text-align: left; padding: 10px 14px; border-bottom: 1px solid #e4e7eb; }
+th { background: #f0f4f8; font-size: .85rem; text-transform: uppercase; letter-spacing: .04em; color: #52606d; }
+td.count { text-align: right; font-variant-numeric: tabular-nums; }
+a { color: #2563eb; text-decoration: none; }
+a:hover { text-decoration: underline; }
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Company}} Exams</h1>
+<p class="summary">{{len .Entries}} exam(s), {{.Total}} question(s)</p>
+{{if .Partial}}<p class="partial">The download was interrupted; some exams are incomplete.</p>
+{{end}}<table>
+<thead><tr><th>Exam</th><th class="count">Questions</th></tr></thead>
+<tbody>
+{{range .Entries}}<tr><td><a href="{{.File}}">{{.Exam}}</a></td><td class="count">{{.Questions}}</td></tr>
+{{end}}</tbody>
+</table>
+</main>
+</body>
+</html>
+`))
+
+// WriteExamIndex writes an index.html into dir that links every exam page of
+// a provider with its question count, and returns its path.
+func WriteExamIndex(dir, provider string, entries []ExamIndexEntry, partial bool) (string, error) {
+	total := 0
+	for _, entry := range entries {
+		total += entry.Questions
+	}
+
+	var page bytes.Buffer
+	err := examIndexTemplate.Execute(&page, struct {
+		Company string
+		Entries []ExamIndexEntry
+		Total   int
+		Partial bool
+	}{
+		Company: providerDisplayName(provider),
+		Entries: entries,
+		Total:   total,
+		Partial: partial,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render exam index: %w", err)
+	}
+
+	indexPath := filepath.Join(dir, "index.html")
+	if err := os.WriteFile(indexPath, page.Bytes(), 0644); err != nil {
+		return "", fmt.Errorf("failed to write exam index: %w", err)
+	}
+	return indexPath, nil
+}
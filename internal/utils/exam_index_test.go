@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteExamIndexLinksEveryExam(t *testing.T) {
+	dir := t.TempDir()
+	entries := []ExamIndexEntry{
+		{Exam: "az-104", File: "microsoft_az-104.html", Questions: 12},
+		{Exam: "az-900", File: "microsoft_az-900 <draft>.html", Questions: 3},
+	}
+
+	indexPath, err := WriteExamIndex(dir, "microsoft", entries, false)
+	if err != nil {
+		t.Fatalf("WriteExamIndex failed: %v", err)
+	}
+	if indexPath != filepath.Join(dir, "index.html") {
+		t.Fatalf("want index at %q, got %q", filepath.Join(dir, "index.html"), indexPath)
+	}
+
+	payload, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatalf("read index: %v", err)
+	}
+	page := string(payload)
+	for _, want := range []string{
+		`<a href="microsoft_az-104.html">az-104</a></td><td class="count">12</td>`,
+		`href="microsoft_az-900%20%3cdraft%3e.html"`,
+		"2 exam(s), 15 question(s)",
+		"<title>Microsoft Exams</title>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Fatalf("index is missing %q:\n%s", want, page)
+		}
+	}
+	if strings.Contains(page, "interrupted") {
+		t.Fatalf("complete index should not carry the partial notice")
+	}
+}
@@ -19,15 +19,22 @@ type answerOption struct {
 }
 
 type templateComment struct {
-	User   string `json:"user"`
-	Answer string `json:"answer"`
-	Text   string `json:"text"`
+	ID          string `json:"id,omitempty"`
+	ParentID    string `json:"parent,omitempty"`
+	User        string `json:"user"`
+	Answer      string `json:"answer"`
+	Text        string `json:"text"`
+	Date        string `json:"date,omitempty"`
+	Upvotes     int    `json:"upvotes,omitempty"`
+	HighlyVoted bool   `json:"highlyVoted,omitempty"`
+	MostRecent  bool   `json:"mostRecent,omitempty"`
 }
 
 type examMeta struct {
 	Company  string
 	ExamCode string
 	Badge    string
+	Partial  bool
 }
 
 var (
@@ -75,8 +82,25 @@ func WriteData(dataList []models.QuestionData, outputPath string, commentBool bo
 	return WriteDataWithSelection(dataList, outputPath, commentBool, "", "")
 }
 
+// RenderOptions controls how WriteDataWithOptions builds the HTML page.
+type RenderOptions struct {
+	IncludeComments bool
+	Provider        string
+	Exam            string
+	// Partial marks the page as built from an interrupted extraction.
+	Partial bool
+}
+
 func WriteDataWithSelection(dataList []models.QuestionData, outputPath string, commentBool bool, selectedProvider string, selectedExam string) ([]string, error) {
-	htmlDoc, err := buildTemplateDocument(dataList, commentBool, selectedProvider, selectedExam)
+	return WriteDataWithOptions(dataList, outputPath, RenderOptions{
+		IncludeComments: commentBool,
+		Provider:        selectedProvider,
+		Exam:            selectedExam,
+	})
+}
+
+func WriteDataWithOptions(dataList []models.QuestionData, outputPath string, opts RenderOptions) ([]string, error) {
+	htmlDoc, err := buildTemplateDocument(dataList, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -89,16 +113,20 @@ func WriteDataWithSelection(dataList []models.QuestionData, outputPath string, c
 	return []string{htmlOutput}, nil
 }
 
-func buildTemplateDocument(dataList []models.QuestionData, includeComments bool, selectedProvider string, selectedExam string) ([]byte, error) {
+func buildTemplateDocument(dataList []models.QuestionData, opts RenderOptions) ([]byte, error) {
 	templateShell, err := readTemplateShell()
 	if err != nil {
 		return nil, err
 	}
 
-	meta := deriveExamMeta(dataList, selectedProvider, selectedExam)
+	meta := deriveExamMeta(dataList, opts.Provider, opts.Exam)
+	meta.Partial = opts.Partial
 	withMeta := applyTemplateMeta(templateShell, meta)
 
-	cardsHTML := buildQuestionCards(dataList, includeComments)
+	cardsHTML := buildQuestionCards(dataList, opts.IncludeComments)
+	if opts.Partial {
+		cardsHTML = renderPartialBanner(len(dataList)) + "\n\n" + cardsHTML
+	}
 	finalDoc, err := injectQuestionCards(withMeta, cardsHTML)
 	if err != nil {
 		return nil, err
@@ -163,6 +191,9 @@ func deriveExamMeta(dataList []models.QuestionData, selectedProvider string, sel
 				}
 			}
 		}
+		if examSlug == "" && item.ExamSlug != "" {
+			examSlug = sanitizeExamSlug(item.ExamSlug)
+		}
 		if examSlug == "" && item.Title != "" {
 			if matches := titleExamPattern.FindStringSubmatch(item.Title); len(matches) == 2 {
 				examSlug = sanitizeExamSlug(matches[1])
@@ -295,6 +326,10 @@ func deriveBadge(provider, examCode, examSlug string) string {
 func applyTemplateMeta(templateHTML string, meta examMeta) string {
 	title := fmt.Sprintf("%s %s Exam Simulator", meta.Company, meta.ExamCode)
 	headerText := fmt.Sprintf("%s Exam Simulator", meta.ExamCode)
+	if meta.Partial {
+		title += " (partial)"
+		headerText += " (partial)"
+	}
 	escapedTitle := htmlpkg.EscapeString(title)
 	escapedHeaderText := htmlpkg.EscapeString(headerText)
 	escapedCompany := htmlpkg.EscapeString(meta.Company)
@@ -429,22 +464,30 @@ func indentBlock(content, indent string) string {
 	return strings.Join(lines, "\n")
 }
 
+// cardPosition identifies a rendered question card.
+type cardPosition struct {
+	ID     string
+	Label  string
+	Topic  int
+	Number int
+}
+
 func buildQuestionCards(dataList []models.QuestionData, includeComments bool) string {
 	var b strings.Builder
-	questionNumber := 0
 
-	for _, data := range dataList {
-		options := parseOptions(data.Questions)
-		if len(options) == 0 {
-			continue
-		}
+	sorted := append([]models.QuestionData(nil), dataList...)
+	SortQuestions(sorted)
+	multiTopic := hasSeveralTopics(sorted)
 
-		questionNumber++
-		qid := fmt.Sprintf("q%d", questionNumber)
-		isOpen := questionNumber == 1
+	rendered := 0
+	usedIDs := map[string]int{}
+	topicGroups := map[int]int{}
+	currentTopic := -1
+	for _, data := range sorted {
+		rendered++
+		pos := questionCardPosition(data, rendered, multiTopic, usedIDs)
+		isOpen := rendered == 1
 
-		correctAnswers := extractCorrectAnswers(data.Answer, options)
-		correct := strings.Join(correctAnswers, ",")
 		link := htmlpkg.EscapeString(strings.TrimSpace(data.QuestionLink))
 		commentsJSON := buildCommentsJSON(data.Comments, includeComments)
 
@@ -453,7 +496,26 @@ func buildQuestionCards(dataList []models.QuestionData, includeComments bool) st
 		if b.Len() > 0 {
 			b.WriteString("\n\n")
 		}
-		b.WriteString(renderQuestionCard(qid, questionNumber, isOpen, correct, link, commentsJSON, questionText, previewText, exhibitURLs, options))
+		if multiTopic && pos.Topic != currentTopic {
+			currentTopic = pos.Topic
+			topicGroups[pos.Topic]++
+			b.WriteString(renderTopicHeader(pos.Topic, topicGroups[pos.Topic]))
+			b.WriteString("\n\n")
+		}
+
+		options := parseOptions(data.Questions)
+		if len(options) == 0 {
+			// Hotspot, drag-and-drop and similar questions have no options
+			// to pick, so their card reveals the suggested answer instead.
+			b.WriteString(renderRevealCard(pos, isOpen, QuestionType(data), link, commentsJSON, questionText, previewText, exhibitURLs, data))
+			continue
+		}
+
+		correctAnswers := extractCorrectAnswers(data.Answer, options)
+		correct := strings.Join(correctAnswers, ",")
+		disputed := CommunityDisagrees(data.Votes, correctAnswers)
+		votesHTML := renderVoteDistribution(pos.ID, data.Votes, correctAnswers, disputed)
+		b.WriteString(renderQuestionCard(pos, isOpen, correct, link, commentsJSON, questionText, previewText, exhibitURLs, options, votesHTML, disputed))
 	}
 
 	if b.Len() == 0 {
@@ -463,9 +525,70 @@ func buildQuestionCards(dataList []models.QuestionData, includeComments bool) st
 	return strings.TrimSpace(b.String())
 }
 
+func hasSeveralTopics(dataList []models.QuestionData) bool {
+	first := -1
+	for _, data := range dataList {
+		topic, _ := QuestionPosition(data)
+		if first == -1 {
+			first = topic
+		} else if topic != first {
+			return true
+		}
+	}
+	return false
+}
+
+// questionCardPosition numbers a card after the exam's own question number,
+// e.g. id "q5" or, for exams with several topics, "t2q5" labelled "T2 Q5".
+// Questions without a number fall back to their position in the page.
+func questionCardPosition(data models.QuestionData, rendered int, multiTopic bool, usedIDs map[string]int) cardPosition {
+	topic, number := QuestionPosition(data)
+	if number == 0 {
+		number = rendered
+	}
+
+	pos := cardPosition{
+		ID:     fmt.Sprintf("q%d", number),
+		Label:  fmt.Sprintf("Q%d", number),
+		Topic:  topic,
+		Number: number,
+	}
+	if multiTopic {
+		pos.ID = fmt.Sprintf("t%dq%d", topic, number)
+		pos.Label = fmt.Sprintf("T%d Q%d", topic, number)
+	}
+
+	// Version variants of an exam can repeat a position.
+	usedIDs[pos.ID]++
+	if n := usedIDs[pos.ID]; n > 1 {
+		pos.ID = fmt.Sprintf("%s-%d", pos.ID, n)
+	}
+	return pos
+}
+
+// renderTopicHeader opens the group-th run of a topic's questions.
+// Unnumbered questions sort last and can reopen a topic, so later runs get
+// their own id.
+func renderTopicHeader(topic, group int) string {
+	label := fmt.Sprintf("Topic %d", topic)
+	if topic == 0 {
+		label = "Other questions"
+	}
+	id := fmt.Sprintf("topic-%d", topic)
+	if group > 1 {
+		id = fmt.Sprintf("%s-%d", id, group)
+	}
+	return fmt.Sprintf("<!-- TOPIC %d -->\n<div class=\"topic-header\" id=\"%s\" data-topic=\"%d\">%s</div>", topic, id, topic, label)
+}
+
+func renderPartialBanner(questionCount int) string {
+	return fmt.Sprintf("<!-- PARTIAL DOWNLOAD -->\n<div class=\"partial-banner\" id=\"partialBanner\">"+
+		"Partial download: the extraction was interrupted, so only %d question(s) are included. "+
+		"Run the download again with --resume to complete it.</div>", questionCount)
+}
+
 func renderQuestionCard(
-	qid string,
-	questionNumber int,
+	pos cardPosition,
 	isOpen bool,
 	correct string,
 	link string,
@@ -474,42 +597,34 @@ func renderQuestionCard(
 	previewText string,
 	exhibitURLs []string,
 	options []answerOption,
+	votesHTML string,
+	disputed bool,
 ) string {
 	var b strings.Builder
+	qid := pos.ID
 
 	cardClass := "q-card"
 	if isOpen {
 		cardClass += " open"
 	}
 
-	fmt.Fprintf(&b, "<!-- QUESTION %d -->\n", questionNumber)
+	fmt.Fprintf(&b, "<!-- QUESTION %s -->\n", pos.Label)
 	fmt.Fprintf(&b, "<div class=\"%s\" id=\"%s\" data-correct=\"%s\"\n", cardClass, qid, correct)
+	fmt.Fprintf(&b, "     data-topic=\"%d\" data-number=\"%d\"\n", pos.Topic, pos.Number)
 	fmt.Fprintf(&b, "     data-link=\"%s\"\n", link)
 	fmt.Fprintf(&b, "     data-comments='%s'>\n", commentsJSON)
 	fmt.Fprintf(&b, "    <div class=\"q-top\" onclick=\"toggleCard('%s')\">\n", qid)
-	fmt.Fprintf(&b, "        <span class=\"q-number\">Q%d</span>\n", questionNumber)
+	fmt.Fprintf(&b, "        <span class=\"q-number\">%s</span>\n", pos.Label)
+	if disputed {
+		b.WriteString("        <span class=\"q-flag\" title=\"The community majority disagrees with the suggested answer\">Disputed</span>\n")
+	}
 	fmt.Fprintf(&b, "        <span class=\"q-preview\" id=\"%s-preview\">%s</span>\n", qid, previewText)
 	fmt.Fprintf(&b, "        <span class=\"q-status\" id=\"%s-status\"></span>\n", qid)
 	b.WriteString("        <span class=\"q-toggle\">&#9662;</span>\n")
 	b.WriteString("    </div>\n")
 	b.WriteString("    <div class=\"q-body\">\n")
 
-	if len(exhibitURLs) > 0 {
-		for idx, exhibitURL := range exhibitURLs {
-			label := "Exhibit"
-			if len(exhibitURLs) > 1 {
-				label = fmt.Sprintf("Exhibit %d", idx+1)
-			}
-
-			fmt.Fprintf(&b, "        <div class=\"q-exhibit\">\n")
-			fmt.Fprintf(&b, "            <span class=\"q-exhibit-label\">%s</span>\n", htmlpkg.EscapeString(label))
-			fmt.Fprintf(&b, "            <img src=\"%s\" alt=\"%s\"\n", htmlpkg.EscapeString(exhibitURL), htmlpkg.EscapeString(label))
-			b.WriteString("                 onerror=\"this.parentElement.style.display='none'\"\n")
-			b.WriteString("                 onclick=\"zoomImage(this.src)\">\n")
-			b.WriteString("            <button class=\"q-exhibit-zoom\" onclick=\"zoomImage(this.parentElement.querySelector('img').src)\" title=\"Zoom image\">+</button>\n")
-			b.WriteString("        </div>\n")
-		}
-	}
+	writeImages(&b, "        ", "Exhibit", exhibitURLs)
 
 	fmt.Fprintf(&b, "        <div class=\"q-text\" id=\"%s-text\">%s</div>\n", qid, questionText)
 
@@ -525,6 +640,7 @@ func renderQuestionCard(
 	b.WriteString("        </div>\n")
 
 	fmt.Fprintf(&b, "        <div class=\"result-bar\" id=\"%s-result\"></div>\n", qid)
+	b.WriteString(votesHTML)
 	b.WriteString("        <div class=\"q-actions\">\n")
 	fmt.Fprintf(&b, "            <button class=\"btn btn-submit\" id=\"%s-submit\" onclick=\"submit('%s')\" disabled>Submit</button>\n", qid, qid)
 	fmt.Fprintf(&b, "            <button class=\"btn btn-cheat\" id=\"%s-cheat\" onclick=\"cheat('%s')\">Sneak Peek</button>\n", qid, qid)
@@ -538,6 +654,87 @@ func renderQuestionCard(
 	return b.String()
 }
 
+func renderRevealCard(
+	pos cardPosition,
+	isOpen bool,
+	questionType string,
+	link string,
+	commentsJSON string,
+	questionText string,
+	previewText string,
+	exhibitURLs []string,
+	data models.QuestionData,
+) string {
+	var b strings.Builder
+	qid := pos.ID
+
+	cardClass := "q-card q-reveal"
+	if isOpen {
+		cardClass += " open"
+	}
+
+	fmt.Fprintf(&b, "<!-- QUESTION %s -->\n", pos.Label)
+	fmt.Fprintf(&b, "<div class=\"%s\" id=\"%s\" data-type=\"%s\"\n", cardClass, qid, htmlpkg.EscapeString(questionType))
+	fmt.Fprintf(&b, "     data-topic=\"%d\" data-number=\"%d\"\n", pos.Topic, pos.Number)
+	fmt.Fprintf(&b, "     data-link=\"%s\"\n", link)
+	fmt.Fprintf(&b, "     data-comments='%s'>\n", commentsJSON)
+	fmt.Fprintf(&b, "    <div class=\"q-top\" onclick=\"toggleCard('%s')\">\n", qid)
+	fmt.Fprintf(&b, "        <span class=\"q-number\">%s</span>\n", pos.Label)
+	fmt.Fprintf(&b, "        <span class=\"q-type\">%s</span>\n", htmlpkg.EscapeString(QuestionTypeLabel(questionType)))
+	fmt.Fprintf(&b, "        <span class=\"q-preview\" id=\"%s-preview\">%s</span>\n", qid, previewText)
+	fmt.Fprintf(&b, "        <span class=\"q-status\" id=\"%s-status\"></span>\n", qid)
+	b.WriteString("        <span class=\"q-toggle\">&#9662;</span>\n")
+	b.WriteString("    </div>\n")
+	b.WriteString("    <div class=\"q-body\">\n")
+
+	fmt.Fprintf(&b, "        <div class=\"q-text\" id=\"%s-text\">%s</div>\n", qid, questionText)
+	writeImages(&b, "        ", "Answer area", exhibitURLs)
+
+	fmt.Fprintf(&b, "        <div class=\"reveal-answer\" id=\"%s-answer\">\n", qid)
+	b.WriteString("            <span class=\"reveal-answer-label\">Suggested answer</span>\n")
+	answerText := htmlpkg.EscapeString(removeSuggestedAnswerText(cleanQuestionText(data.Answer)))
+	switch {
+	case len(data.AnswerImageURLs) > 0:
+		writeImages(&b, "            ", "Answer", data.AnswerImageURLs)
+	case answerText != "":
+		fmt.Fprintf(&b, "            <div class=\"reveal-answer-text\">%s</div>\n", answerText)
+	default:
+		b.WriteString("            <div class=\"reveal-answer-text\">No suggested answer was captured; see the discussion.</div>\n")
+	}
+	b.WriteString("        </div>\n")
+
+	fmt.Fprintf(&b, "        <div class=\"result-bar\" id=\"%s-result\"></div>\n", qid)
+	b.WriteString("        <div class=\"q-actions\">\n")
+	fmt.Fprintf(&b, "            <button class=\"btn btn-cheat\" id=\"%s-cheat\" onclick=\"reveal('%s')\">Reveal Answer</button>\n", qid, qid)
+	fmt.Fprintf(&b, "            <a class=\"btn btn-discuss hidden\" id=\"%s-discuss\" href=\"#\" target=\"_blank\">ExamTopics</a>\n", qid)
+	fmt.Fprintf(&b, "            <button class=\"btn btn-comments\" id=\"%s-comments\" onclick=\"openComments('%s')\">Comments</button>\n", qid, qid)
+	fmt.Fprintf(&b, "            <button class=\"btn btn-reset hidden\" id=\"%s-reset\" onclick=\"reset('%s')\">Hide</button>\n", qid, qid)
+	b.WriteString("        </div>\n")
+	b.WriteString("    </div>\n")
+	b.WriteString("</div>")
+
+	return b.String()
+}
+
+// writeImages renders zoomable images labelled "<label>" or, for several,
+// "<label> 1", "<label> 2" and so on.
+func writeImages(b *strings.Builder, indent, baseLabel string, urls []string) {
+	for idx, imageURL := range urls {
+		label := baseLabel
+		if len(urls) > 1 {
+			label = fmt.Sprintf("%s %d", baseLabel, idx+1)
+		}
+
+		fmt.Fprintf(b, "%s<div class=\"q-exhibit\">\n", indent)
+		fmt.Fprintf(b, "%s    <span class=\"q-exhibit-label\">%s</span>\n", indent, htmlpkg.EscapeString(label))
+		fmt.Fprintf(b, "%s    <img src=\"%s\" alt=\"%s\"\n", indent, htmlpkg.EscapeString(imageURL), htmlpkg.EscapeString(label))
+		fmt.Fprintf(b, "%s         onerror=\"this.parentElement.style.display='none'\"\n", indent)
+		fmt.Fprintf(b, "%s         onclick=\"zoomImage(this.src)\">\n", indent)
+		fmt.Fprintf(b, "%s    <button class=\"q-exhibit-zoom\" onclick=\"zoomImage(this.parentElement.querySelector('img').src)\" title=\"Zoom image\">+</button>\n", indent)
+		fmt.Fprintf(b, "%s</div>\n", indent)
+	}
+}
+
 func buildQuestionTextAndPreview(data models.QuestionData) (string, string, []string) {
 	exhibitURLs := extractExhibitURLs(data)
 
@@ -546,9 +743,12 @@ func buildQuestionTextAndPreview(data models.QuestionData) (string, string, []st
 	title := removeSuggestedAnswerText(cleanQuestionText(stripImageURLs(data.Title)))
 
 	var body string
+	richBody := ""
 	switch {
 	case content != "" && !looksLikeExamMetadata(content):
 		body = content
+		// Datasets can be edited by hand, so sanitize again.
+		richBody = SanitizeHTML(data.ContentHTML)
 	case header != "" && !looksLikeExamMetadata(header):
 		body = header
 	case content != "":
@@ -564,7 +764,10 @@ func buildQuestionTextAndPreview(data models.QuestionData) (string, string, []st
 	}
 
 	preview := truncatePreview(body, 95)
-	formattedBody := formatHTMLText(body)
+	formattedBody := richBody
+	if formattedBody == "" {
+		formattedBody = formatHTMLText(body)
+	}
 
 	return formattedBody, htmlpkg.EscapeString(preview), exhibitURLs
 }
@@ -815,9 +1018,15 @@ func buildCommentsJSON(raw []models.CommentData, includeComments bool) string {
 			}
 
 			comments = append(comments, templateComment{
-				User:   user,
-				Answer: strings.ToUpper(strings.TrimSpace(comment.Answer)),
-				Text:   strings.TrimSpace(comment.Text),
+				ID:          comment.ID,
+				ParentID:    comment.ParentID,
+				User:        user,
+				Answer:      strings.ToUpper(strings.TrimSpace(comment.Answer)),
+				Text:        strings.TrimSpace(comment.Text),
+				Date:        comment.Date,
+				Upvotes:     comment.Upvotes,
+				HighlyVoted: comment.HighlyVoted,
+				MostRecent:  comment.MostRecent,
 			})
 		}
 	}
@@ -0,0 +1,203 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"examtopics-downloader/internal/models"
+)
+
+// maxProxyFailures is how many failures in a row take a proxy out of
+// rotation.
+const maxProxyFailures = 3
+
+// ErrNoProxiesLeft is returned once every proxy in the list has been taken
+// out of rotation.
+var ErrNoProxiesLeft = errors.New("every proxy has been taken out of rotation")
+
+var (
+	proxyURLs        []*url.URL
+	proxyRemovedHook func(proxy string, err error)
+)
+
+// ParseProxyURL accepts http, https, socks5 and socks5h proxies. A value
+// without a scheme is taken as an HTTP proxy.
+func ParseProxyURL(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("empty proxy URL")
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %q: %w", raw, err)
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy %q: scheme must be http, https, socks5 or socks5h", parsed.Redacted())
+	}
+	if parsed.Hostname() == "" {
+		return nil, fmt.Errorf("invalid proxy %q: missing host", parsed.Redacted())
+	}
+	return parsed, nil
+}
+
+// SetProxies routes clients created afterwards through the given proxies.
+// One proxy is used for everything; several are rotated per request. With
+// none, the standard HTTP_PROXY/HTTPS_PROXY/NO_PROXY variables apply.
+func SetProxies(raw []string) error {
+	parsed := make([]*url.URL, 0, len(raw))
+	for _, entry := range raw {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		proxyURL, err := ParseProxyURL(entry)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, proxyURL)
+	}
+	proxyURLs = parsed
+	return nil
+}
+
+// ReadProxyList reads one proxy URL per line, skipping blank lines and
+// lines starting with #.
+func ReadProxyList(path string) ([]string, error) {
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read proxy list %q: %w", path, err)
+	}
+
+	var proxies []string
+	for _, line := range strings.Split(string(payload), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		proxies = append(proxies, line)
+	}
+	if len(proxies) == 0 {
+		return nil, fmt.Errorf("proxy list %q has no entries", path)
+	}
+	return proxies, nil
+}
+
+// SetProxyRemovedHook registers a callback for proxies taken out of
+// rotation. The proxy is passed with its password redacted.
+func SetProxyRemovedHook(fn func(proxy string, err error)) {
+	proxyRemovedHook = fn
+}
+
+func newProxiedTransport() http.RoundTripper {
+	switch len(proxyURLs) {
+	case 0:
+		transport := newTransport()
+		transport.Proxy = http.ProxyFromEnvironment
+		return transport
+	case 1:
+		transport := newTransport()
+		transport.Proxy = http.ProxyURL(proxyURLs[0])
+		return transport
+	default:
+		return newProxyRotator(proxyURLs)
+	}
+}
+
+func newTransport() *http.Transport {
+	transport := models.OptimizedTransport()
+	transport.TLSHandshakeTimeout = httpTimeouts.TLSHandshake
+	transport.ResponseHeaderTimeout = httpTimeouts.ResponseHeader
+	transport.IdleConnTimeout = httpTimeouts.IdleConn
+	return transport
+}
+
+// proxyRotator spreads requests round-robin over several proxies, each with
+// its own transport, and drops a proxy after maxProxyFailures failures in a
+// row.
+type proxyRotator struct {
+	mu      sync.Mutex
+	proxies []*rotatingProxy
+	next    int
+}
+
+type rotatingProxy struct {
+	url       *url.URL
+	transport *http.Transport
+	failures  int
+	removed   bool
+}
+
+func newProxyRotator(urls []*url.URL) *proxyRotator {
+	r := &proxyRotator{}
+	for _, proxyURL := range urls {
+		transport := newTransport()
+		transport.Proxy = http.ProxyURL(proxyURL)
+		r.proxies = append(r.proxies, &rotatingProxy{url: proxyURL, transport: transport})
+	}
+	return r
+}
+
+func (r *proxyRotator) RoundTrip(req *http.Request) (*http.Response, error) {
+	proxy := r.pick()
+	if proxy == nil {
+		return nil, ErrNoProxiesLeft
+	}
+
+	resp, err := proxy.transport.RoundTrip(req)
+	switch {
+	case err != nil && req.Context().Err() == nil:
+		r.fail(proxy, err)
+	case err == nil && resp.StatusCode == http.StatusProxyAuthRequired:
+		r.fail(proxy, fmt.Errorf("proxy answered %s", resp.Status))
+	case err == nil:
+		r.succeed(proxy)
+	}
+	return resp, err
+}
+
+func (r *proxyRotator) pick() *rotatingProxy {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for range r.proxies {
+		proxy := r.proxies[r.next]
+		r.next = (r.next + 1) % len(r.proxies)
+		if !proxy.removed {
+			return proxy
+		}
+	}
+	return nil
+}
+
+func (r *proxyRotator) fail(proxy *rotatingProxy, err error) {
+	r.mu.Lock()
+	proxy.failures++
+	remove := !proxy.removed && proxy.failures >= maxProxyFailures
+	if remove {
+		proxy.removed = true
+	}
+	r.mu.Unlock()
+
+	if remove {
+		proxy.transport.CloseIdleConnections()
+		if hook := proxyRemovedHook; hook != nil {
+			hook(proxy.url.Redacted(), err)
+		}
+	}
+}
+
+func (r *proxyRotator) succeed(proxy *rotatingProxy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	proxy.failures = 0
+}
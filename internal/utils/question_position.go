@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+
+	"examtopics-downloader/internal/models"
+)
+
+var (
+	linkTopicPattern    = regexp.MustCompile(`(?i)topic-(\d+)`)
+	linkQuestionPattern = regexp.MustCompile(`(?i)question-(\d+)`)
+)
+
+// QuestionPositionFromLink returns the topic and question number named by a
+// discussion link such as ".../1-exam-200-301-topic-2-question-5-discussion/",
+// with 0 for a part the link does not name.
+func QuestionPositionFromLink(link string) (topic, number int) {
+	if m := linkTopicPattern.FindStringSubmatch(link); len(m) == 2 {
+		topic, _ = strconv.Atoi(m[1])
+	}
+	if m := linkQuestionPattern.FindStringSubmatch(link); len(m) == 2 {
+		number, _ = strconv.Atoi(m[1])
+	}
+	return topic, number
+}
+
+// QuestionPosition returns the topic and number of a question. Datasets
+// saved before the scraper recorded them fall back to the question link.
+func QuestionPosition(data models.QuestionData) (topic, number int) {
+	topic, number = data.Topic, data.QuestionNumber
+	if topic == 0 || number == 0 {
+		linkTopic, linkNumber := QuestionPositionFromLink(data.QuestionLink)
+		if topic == 0 {
+			topic = linkTopic
+		}
+		if number == 0 {
+			number = linkNumber
+		}
+	}
+	return topic, number
+}
+
+// positionLess orders by topic, then question number. Positions without a
+// question number sort after numbered ones.
+func positionLess(topicI, numberI, topicJ, numberJ int) bool {
+	if (numberI == 0) != (numberJ == 0) {
+		return numberJ == 0
+	}
+	if topicI != topicJ {
+		return topicI < topicJ
+	}
+	return numberI < numberJ
+}
+
+// SortQuestions orders questions by topic and question number, keeping the
+// original order of questions at the same position.
+func SortQuestions(questions []models.QuestionData) {
+	sort.SliceStable(questions, func(i, j int) bool {
+		topicI, numberI := QuestionPosition(questions[i])
+		topicJ, numberJ := QuestionPosition(questions[j])
+		return positionLess(topicI, numberI, topicJ, numberJ)
+	})
+}
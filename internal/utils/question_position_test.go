@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"examtopics-downloader/internal/models"
+)
+
+func TestSortQuestionsOrdersByTopicThenNumber(t *testing.T) {
+	link := "https://www.examtopics.com/discussions/microsoft/view/%s/"
+	questions := []models.QuestionData{
+		{Title: "t2q5", Topic: 2, QuestionNumber: 5},
+		{Title: "unnumbered"},
+		{Title: "t1q10", Topic: 1, QuestionNumber: 10},
+		// Saved before positions were recorded: read from the link.
+		{Title: "t1q5", QuestionLink: strings.Replace(link, "%s", "9-exam-az-104-topic-1-question-5-discussion", 1)},
+		{Title: "t2q1", Topic: 2, QuestionNumber: 1},
+	}
+
+	SortQuestions(questions)
+
+	want := []string{"t1q5", "t1q10", "t2q1", "t2q5", "unnumbered"}
+	for i, title := range want {
+		if questions[i].Title != title {
+			t.Fatalf("position %d: want %q, got %q", i, title, questions[i].Title)
+		}
+	}
+}
+
+func TestBuildQuestionCardsGroupsTopics(t *testing.T) {
+	question := func(topic, number int) models.QuestionData {
+		return models.QuestionData{
+			Content:        "Which option?",
+			Questions:      []string{"A. one", "B. two"},
+			Answer:         "A",
+			Topic:          topic,
+			QuestionNumber: number,
+		}
+	}
+
+	cards := buildQuestionCards([]models.QuestionData{question(2, 5), question(1, 5), question(1, 5)}, false)
+
+	for _, want := range []string{
+		`id="topic-1" data-topic="1">Topic 1</div>`,
+		`id="t1q5" data-correct="A"`,
+		`id="t1q5-2" data-correct="A"`,
+		`id="t2q5" data-correct="A"`,
+		`data-topic="2" data-number="5"`,
+		`<span class="q-number">T2 Q5</span>`,
+	} {
+		if !strings.Contains(cards, want) {
+			t.Fatalf("cards are missing %q:\n%s", want, cards)
+		}
+	}
+	if strings.Index(cards, `id="topic-2"`) < strings.Index(cards, `id="t1q5-2"`) {
+		t.Fatalf("topic 2 should follow every topic 1 question:\n%s", cards)
+	}
+
+	// An unnumbered topic 1 question sorts after topic 2 and reopens topic 1.
+	reopened := buildQuestionCards([]models.QuestionData{question(1, 1), question(2, 1), question(1, 0)}, false)
+	for _, id := range []string{`id="topic-1"`, `id="topic-2"`, `id="topic-1-2"`} {
+		if strings.Count(reopened, id) != 1 {
+			t.Fatalf("want %s exactly once:\n%s", id, reopened)
+		}
+	}
+
+	single := buildQuestionCards([]models.QuestionData{question(1, 7)}, false)
+	if strings.Contains(single, "topic-header") || !strings.Contains(single, `id="q7"`) {
+		t.Fatalf("single-topic exam should use plain question ids:\n%s", single)
+	}
+}
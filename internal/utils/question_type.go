@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"examtopics-downloader/internal/models"
+)
+
+var (
+	hotspotPattern  = regexp.MustCompile(`(?i)\bhot\s*spot\b|\bhot area\b`)
+	dragDropPattern = regexp.MustCompile(`(?i)\bdrag\s*(?:and\s*)?drop\b|\bdrag the appropriate\b`)
+	yesNoPattern    = regexp.MustCompile(`(?i)\bselect yes if\b|\bfor each of the following statements\b`)
+)
+
+// ClassifyQuestion returns the type of a question. A question with lettered
+// options is multiple choice; the others are told apart by the instructions
+// ExamTopics copies from the exam ("HOTSPOT", "DRAG DROP", "select Yes if").
+func ClassifyQuestion(data models.QuestionData) string {
+	if len(parseOptions(data.Questions)) > 0 {
+		return models.QuestionTypeMultipleChoice
+	}
+
+	text := strings.Join([]string{data.Content, data.Header}, "\n")
+	switch {
+	case dragDropPattern.MatchString(text):
+		return models.QuestionTypeDragAndDrop
+	case hotspotPattern.MatchString(text):
+		return models.QuestionTypeHotspot
+	case yesNoPattern.MatchString(text):
+		return models.QuestionTypeYesNo
+	}
+	return models.QuestionTypeOther
+}
+
+// QuestionType returns the recorded type of a question, classifying
+// questions from datasets saved before types were recorded.
+func QuestionType(data models.QuestionData) string {
+	if data.Type != "" {
+		return data.Type
+	}
+	return ClassifyQuestion(data)
+}
+
+// QuestionTypeLabel is the human-readable name of a question type.
+func QuestionTypeLabel(questionType string) string {
+	switch questionType {
+	case models.QuestionTypeMultipleChoice:
+		return "Multiple choice"
+	case models.QuestionTypeHotspot:
+		return "Hotspot"
+	case models.QuestionTypeDragAndDrop:
+		return "Drag and drop"
+	case models.QuestionTypeYesNo:
+		return "Yes/No"
+	}
+	return "Answer area"
+}
+
+// QuestionTypeCount is how many questions of one type an exam has.
+type QuestionTypeCount struct {
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+// CountQuestionTypes counts the questions of each type, most common first.
+func CountQuestionTypes(questions []models.QuestionData) []QuestionTypeCount {
+	counts := map[string]int{}
+	for _, question := range questions {
+		counts[QuestionType(question)]++
+	}
+
+	out := make([]QuestionTypeCount, 0, len(counts))
+	for questionType, count := range counts {
+		out = append(out, QuestionTypeCount{Type: questionType, Count: count})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Type < out[j].Type
+	})
+	return out
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"examtopics-downloader/internal/models"
+)
+
+func TestClassifyQuestion(t *testing.T) {
+	tests := []struct {
+		data models.QuestionData
+		want string
+	}{
+		{data: models.QuestionData{Content: "DRAG DROP - Drag the appropriate commands", Questions: []string{"A. one", "B. two"}}, want: models.QuestionTypeMultipleChoice},
+		{data: models.QuestionData{Content: "HOTSPOT - Select the appropriate options in the answer area."}, want: models.QuestionTypeHotspot},
+		{data: models.QuestionData{Content: "DRAG DROP - You need to configure..."}, want: models.QuestionTypeDragAndDrop},
+		{data: models.QuestionData{Content: "For each of the following statements, select Yes if the statement is true."}, want: models.QuestionTypeYesNo},
+		{data: models.QuestionData{Content: "Complete the configuration."}, want: models.QuestionTypeOther},
+	}
+
+	for _, tc := range tests {
+		if got := ClassifyQuestion(tc.data); got != tc.want {
+			t.Fatalf("%q: want %q, got %q", tc.data.Content, tc.want, got)
+		}
+	}
+}
+
+func TestCountQuestionTypesMostCommonFirst(t *testing.T) {
+	questions := []models.QuestionData{
+		{Type: models.QuestionTypeHotspot},
+		{Questions: []string{"A. one", "B. two"}},
+		{Type: models.QuestionTypeMultipleChoice},
+		{Type: models.QuestionTypeDragAndDrop},
+	}
+
+	got := CountQuestionTypes(questions)
+	want := []QuestionTypeCount{
+		{Type: models.QuestionTypeMultipleChoice, Count: 2},
+		{Type: models.QuestionTypeDragAndDrop, Count: 1},
+		{Type: models.QuestionTypeHotspot, Count: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %v, got %v", want, got)
+	}
+}
+
+func TestQuestionsWithoutOptionsGetARevealCard(t *testing.T) {
+	hotspot := models.QuestionData{
+		Content:         "HOTSPOT - Select the answer.",
+		ExhibitURLs:     []string{"https://img.examtopics.com/az-104/image10.png"},
+		AnswerImageURLs: []string{"https://img.examtopics.com/az-104/image11.png"},
+		Type:            models.QuestionTypeHotspot,
+		QuestionNumber:  4,
+	}
+
+	cards := buildQuestionCards([]models.QuestionData{hotspot}, false)
+	for _, want := range []string{
+		`class="q-card q-reveal open" id="q4" data-type="hotspot"`,
+		`<span class="q-type">Hotspot</span>`,
+		`<span class="q-exhibit-label">Answer area</span>`,
+		`<img src="https://img.examtopics.com/az-104/image11.png" alt="Answer"`,
+		`onclick="reveal('q4')"`,
+	} {
+		if !strings.Contains(cards, want) {
+			t.Fatalf("reveal card is missing %q:\n%s", want, cards)
+		}
+	}
+	if strings.Contains(cards, "q4-opts") {
+		t.Fatalf("reveal card should have no options:\n%s", cards)
+	}
+}
@@ -0,0 +1,125 @@
+package utils
+
+import (
+	htmlpkg "html"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// allowedTags keep their structure in sanitized question HTML. Any other
+// element is replaced by its children, except droppedTags.
+var allowedTags = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Br: true, atom.Hr: true,
+	atom.Pre: true, atom.Code: true, atom.Kbd: true, atom.Samp: true, atom.Blockquote: true,
+	atom.B: true, atom.Strong: true, atom.I: true, atom.Em: true, atom.U: true, atom.S: true,
+	atom.Sub: true, atom.Sup: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true,
+	atom.Table: true, atom.Caption: true, atom.Thead: true, atom.Tbody: true, atom.Tfoot: true,
+	atom.Tr: true, atom.Th: true, atom.Td: true,
+	atom.A: true,
+}
+
+// droppedTags are removed together with their content. Images are dropped
+// because exhibits are rendered from QuestionData.ExhibitURLs.
+var droppedTags = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Iframe: true, atom.Object: true, atom.Embed: true, atom.Svg: true, atom.Math: true,
+	atom.Form: true, atom.Input: true, atom.Button: true, atom.Select: true, atom.Textarea: true,
+	atom.Img: true, atom.Picture: true, atom.Video: true, atom.Audio: true,
+}
+
+// SanitizeHTML reduces an HTML fragment to the allowlisted formatting tags:
+// paragraphs, line breaks, code blocks, lists, tables and inline emphasis.
+// Attributes are dropped except table cell spans and http(s) link targets.
+// It returns "" when the fragment has no text.
+func SanitizeHTML(fragment string) string {
+	if strings.TrimSpace(fragment) == "" {
+		return ""
+	}
+
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), context)
+	if err != nil {
+		return ""
+	}
+
+	var b strings.Builder
+	hasText := false
+	for _, node := range nodes {
+		writeSanitized(&b, node, &hasText)
+	}
+	if !hasText {
+		return ""
+	}
+	return strings.TrimSpace(b.String())
+}
+
+func writeSanitized(b *strings.Builder, node *html.Node, hasText *bool) {
+	switch node.Type {
+	case html.TextNode:
+		if strings.TrimSpace(node.Data) != "" {
+			*hasText = true
+		}
+		b.WriteString(htmlpkg.EscapeString(node.Data))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	if droppedTags[node.DataAtom] {
+		return
+	}
+	if !allowedTags[node.DataAtom] {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			writeSanitized(b, child, hasText)
+		}
+		return
+	}
+
+	tag := node.DataAtom.String()
+	if node.DataAtom == atom.Br || node.DataAtom == atom.Hr {
+		b.WriteString("<" + tag + ">")
+		return
+	}
+	var inner strings.Builder
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		writeSanitized(&inner, child, hasText)
+	}
+	// Stray closing tags in the source leave empty paragraphs behind.
+	if (node.DataAtom == atom.P || node.DataAtom == atom.Div) && strings.TrimSpace(inner.String()) == "" {
+		return
+	}
+	attrs := sanitizedAttributes(node)
+	if node.DataAtom == atom.A && attrs == "" {
+		// A link without a safe target is kept as plain text.
+		b.WriteString(inner.String())
+		return
+	}
+	b.WriteString("<" + tag + attrs + ">")
+	b.WriteString(inner.String())
+	b.WriteString("</" + tag + ">")
+}
+
+func sanitizedAttributes(node *html.Node) string {
+	var b strings.Builder
+	for _, attr := range node.Attr {
+		switch {
+		case (node.DataAtom == atom.Td || node.DataAtom == atom.Th) && (attr.Key == "colspan" || attr.Key == "rowspan"):
+			if n, err := strconv.Atoi(strings.TrimSpace(attr.Val)); err == nil && n > 0 && n <= 100 {
+				b.WriteString(" " + attr.Key + `="` + strconv.Itoa(n) + `"`)
+			}
+		case node.DataAtom == atom.A && attr.Key == "href":
+			u, err := url.Parse(strings.TrimSpace(attr.Val))
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				continue
+			}
+			b.WriteString(` href="` + htmlpkg.EscapeString(u.String()) + `" target="_blank" rel="noopener noreferrer"`)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"testing"
+
+	"examtopics-downloader/internal/models"
+)
+
+func TestSanitizeHTMLKeepsStructure(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "code block keeps line breaks",
+			input: "<p class=\"card-text\">Run:<br>\n<pre><code>az vm create \\\n  --name vm1</code></pre></p>",
+			want:  "<p>Run:<br>\n</p><pre><code>az vm create \\\n  --name vm1</code></pre>",
+		},
+		{
+			name:  "table with spans",
+			input: `<table class="x"><tr><th colspan="2" style="color:red">Port</th></tr><tr><td rowspan="abc">22</td><td>SSH</td></tr></table>`,
+			want:  `<table><tbody><tr><th colspan="2">Port</th></tr><tr><td>22</td><td>SSH</td></tr></tbody></table>`,
+		},
+		{
+			name:  "unknown tags are unwrapped and unsafe ones dropped",
+			input: `<ul><li><span onclick="x()">one</span></li><li>two<script>alert(1)</script></li></ul><img src="https://img.examtopics.com/a.png">`,
+			want:  `<ul><li>one</li><li>two</li></ul>`,
+		},
+		{
+			name:  "only http links survive",
+			input: `<a href="javascript:alert(1)">bad</a> <a href="https://learn.microsoft.com/?a=1&b=2">docs</a>`,
+			want:  `bad <a href="https://learn.microsoft.com/?a=1&amp;b=2" target="_blank" rel="noopener noreferrer">docs</a>`,
+		},
+		{
+			name:  "text is escaped",
+			input: `<p>if a &lt; b &amp;&amp; c</p>`,
+			want:  `<p>if a &lt; b &amp;&amp; c</p>`,
+		},
+		{
+			name:  "no text",
+			input: `<p><img src="https://img.examtopics.com/a.png"></p>`,
+			want:  "",
+		},
+	}
+
+	for _, tc := range tests {
+		if got := SanitizeHTML(tc.input); got != tc.want {
+			t.Fatalf("%s: want %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestQuestionTextPrefersSanitizedHTML(t *testing.T) {
+	data := models.QuestionData{
+		Content:     "Which command lists VMs? az vm list",
+		ContentHTML: `<p>Which command lists VMs?</p><pre><code>az vm list</code></pre><script>alert(1)</script>`,
+	}
+
+	text, preview, _ := buildQuestionTextAndPreview(data)
+	if text != "<p>Which command lists VMs?</p><pre><code>az vm list</code></pre>" {
+		t.Fatalf("unexpected question HTML: %q", text)
+	}
+	if preview != "Which command lists VMs? az vm list" {
+		t.Fatalf("preview should come from the plain text, got %q", preview)
+	}
+
+	data.ContentHTML = ""
+	if text, _, _ := buildQuestionTextAndPreview(data); text != "Which command lists VMs? az vm list" {
+		t.Fatalf("without HTML the plain text should be used, got %q", text)
+	}
+}
@@ -1,8 +1,8 @@
 package utils
 
 import (
+	"context"
 	"examtopics-downloader/internal/constants"
-	"examtopics-downloader/internal/models"
 	"fmt"
 	"math/rand"
 	"net/http"
@@ -10,7 +10,6 @@ import (
 	"regexp"
 	"runtime"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -69,39 +68,13 @@ func DeduplicateLinks(links []string) []string {
 	return unique
 }
 
+// SortLinksByQuestionNumber orders discussion links by the topic and
+// question number they name, see QuestionPositionFromLink.
 func SortLinksByQuestionNumber(links []string) []string {
-	extractQuestionNum := func(url string) int {
-		parts := strings.Split(url, "question-")
-		if len(parts) < 2 {
-			return 0
-		}
-		numStr := strings.TrimSuffix(parts[1], "/")
-		numStr = strings.TrimSuffix(numStr, "-discussion")
-		num, _ := strconv.Atoi(numStr)
-		return num
-	}
-
-	extractTopicNum := func(url string) int {
-		parts := strings.Split(url, "topic-")
-		if len(parts) < 2 {
-			return 0
-		}
-		subParts := strings.Split(parts[1], "-")
-		if len(subParts) < 1 {
-			return 0
-		}
-		num, _ := strconv.Atoi(subParts[0])
-		return num
-	}
-
-	sort.Slice(links, func(i, j int) bool {
-		topicI := extractTopicNum(links[i])
-		topicJ := extractTopicNum(links[j])
-
-		if topicI != topicJ {
-			return topicI < topicJ
-		}
-		return extractQuestionNum(links[i]) < extractQuestionNum(links[j])
+	sort.SliceStable(links, func(i, j int) bool {
+		topicI, numberI := QuestionPositionFromLink(links[i])
+		topicJ, numberJ := QuestionPositionFromLink(links[j])
+		return positionLess(topicI, numberI, topicJ, numberJ)
 	})
 	return links
 }
@@ -114,7 +87,7 @@ func GrepString(baseString, searchString string) bool {
 }
 
 func AddToBaseUrl(addString string) string {
-	return fmt.Sprintf("https://www.examtopics.com%s", addString)
+	return BaseURL() + addString
 }
 
 func CreateRateLimiter(rps float64) *time.Ticker {
@@ -134,11 +107,46 @@ func Sleep(seconds time.Duration) {
 	time.Sleep(seconds)
 }
 
-// NewHTTPClient creates an optimized HTTP client
+// SleepContext waits for d, returning early with the context's error if it
+// is cancelled first.
+func SleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// HTTPTimeouts are the client and transport timeouts used by NewHTTPClient.
+type HTTPTimeouts struct {
+	Request        time.Duration
+	TLSHandshake   time.Duration
+	ResponseHeader time.Duration
+	IdleConn       time.Duration
+}
+
+var httpTimeouts = HTTPTimeouts{
+	Request:        constants.HttpTimeout,
+	TLSHandshake:   constants.TLSHandshakeTimeout,
+	ResponseHeader: constants.ResponseHeaderTimeout,
+	IdleConn:       constants.IdleConnTimeout,
+}
+
+// SetHTTPTimeouts changes the timeouts of clients created afterwards.
+func SetHTTPTimeouts(t HTTPTimeouts) {
+	httpTimeouts = t
+}
+
+// NewHTTPClient creates an optimized HTTP client that goes through the
+// proxies set with SetProxies.
 func NewHTTPClient() *http.Client {
 	return &http.Client{
-		Timeout:   constants.HttpTimeout,
-		Transport: models.OptimizedTransport(),
+		Timeout:   httpTimeouts.Request,
+		Transport: newProxiedTransport(),
 	}
 }
 
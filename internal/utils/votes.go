@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"fmt"
+	htmlpkg "html"
+	"sort"
+	"strconv"
+	"strings"
+
+	"examtopics-downloader/internal/models"
+)
+
+// CommunityDisagrees reports whether the most voted answer differs from the
+// suggested answer. A tie for the most votes that includes the suggested
+// answer is not a disagreement.
+func CommunityDisagrees(votes []models.VoteShare, correctAnswers []string) bool {
+	if len(votes) == 0 || len(correctAnswers) == 0 {
+		return false
+	}
+
+	suggested := sortedLetters(strings.Join(correctAnswers, ""))
+	top := votes[0].Percent
+	for _, vote := range votes {
+		if vote.Percent < top {
+			break
+		}
+		if sortedLetters(vote.Answer) == suggested {
+			return false
+		}
+	}
+	return true
+}
+
+// sortedLetters normalizes an answer such as "D, B" to "BD".
+func sortedLetters(answer string) string {
+	letters := answerLettersRunes.FindAllString(strings.ToUpper(answer), -1)
+	sort.Strings(letters)
+	return strings.Join(letters, "")
+}
+
+// renderVoteDistribution renders the community vote bar shown once the
+// question is answered.
+func renderVoteDistribution(qid string, votes []models.VoteShare, correctAnswers []string, disputed bool) string {
+	if len(votes) == 0 {
+		return ""
+	}
+
+	suggested := sortedLetters(strings.Join(correctAnswers, ""))
+	var b strings.Builder
+	fmt.Fprintf(&b, "        <div class=\"vote-dist\" id=\"%s-votes\">\n", qid)
+	b.WriteString("            <span class=\"vote-dist-label\">Community vote distribution</span>\n")
+	b.WriteString("            <div class=\"vote-bar\">\n")
+	for _, vote := range votes {
+		answer := htmlpkg.EscapeString(vote.Answer)
+		percent := strconv.FormatFloat(vote.Percent, 'f', -1, 64)
+		title := fmt.Sprintf("%s: %s%%", answer, percent)
+		if vote.Count > 0 {
+			title += fmt.Sprintf(" (%d votes)", vote.Count)
+		}
+		segmentClass := "vote-seg"
+		if sortedLetters(vote.Answer) == suggested {
+			segmentClass += " is-suggested"
+		}
+		fmt.Fprintf(&b, "                <span class=\"%s\" style=\"width:%s%%\" title=\"%s\">%s %s%%</span>\n", segmentClass, percent, title, answer, percent)
+	}
+	b.WriteString("            </div>\n")
+	if disputed {
+		fmt.Fprintf(&b, "            <div class=\"vote-note\">The community majority (%s) disagrees with the suggested answer (%s).</div>\n",
+			htmlpkg.EscapeString(votes[0].Answer), htmlpkg.EscapeString(strings.Join(correctAnswers, ", ")))
+	}
+	b.WriteString("        </div>\n")
+	return b.String()
+}
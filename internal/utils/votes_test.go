@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"examtopics-downloader/internal/models"
+)
+
+func TestCommunityDisagrees(t *testing.T) {
+	tests := []struct {
+		name    string
+		votes   []models.VoteShare
+		correct []string
+		want    bool
+	}{
+		{name: "agrees", votes: []models.VoteShare{{Answer: "B", Percent: 80}, {Answer: "C", Percent: 20}}, correct: []string{"B"}},
+		{name: "disagrees", votes: []models.VoteShare{{Answer: "C", Percent: 60}, {Answer: "B", Percent: 40}}, correct: []string{"B"}, want: true},
+		{name: "letters in any order", votes: []models.VoteShare{{Answer: "DB", Percent: 90}}, correct: []string{"B", "D"}},
+		{name: "tie with the suggested answer", votes: []models.VoteShare{{Answer: "A", Percent: 50}, {Answer: "C", Percent: 50}}, correct: []string{"C"}},
+		{name: "no votes", correct: []string{"A"}},
+	}
+
+	for _, tc := range tests {
+		if got := CommunityDisagrees(tc.votes, tc.correct); got != tc.want {
+			t.Fatalf("%s: want %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestDisputedQuestionShowsVotes(t *testing.T) {
+	data := models.QuestionData{
+		Questions:      []string{"A. one", "B. two", "C. three"},
+		Answer:         "B",
+		Votes:          []models.VoteShare{{Answer: "C", Count: 6, Percent: 60}, {Answer: "B", Count: 4, Percent: 40}},
+		QuestionNumber: 7,
+	}
+
+	cards := buildQuestionCards([]models.QuestionData{data}, false)
+	for _, want := range []string{
+		`<span class="q-flag"`,
+		`<div class="vote-dist" id="q7-votes">`,
+		`<span class="vote-seg" style="width:60%" title="C: 60% (6 votes)">C 60%</span>`,
+		`<span class="vote-seg is-suggested" style="width:40%"`,
+		`The community majority (C) disagrees with the suggested answer (B).`,
+	} {
+		if !strings.Contains(cards, want) {
+			t.Fatalf("card is missing %q:\n%s", want, cards)
+		}
+	}
+
+	data.Votes = data.Votes[1:]
+	if cards := buildQuestionCards([]models.QuestionData{data}, false); strings.Contains(cards, "q-flag") || strings.Contains(cards, "vote-note") {
+		t.Fatalf("a question the community agrees with should not be flagged:\n%s", cards)
+	}
+}